@@ -1,27 +1,76 @@
 package asl
 
-/*
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterstace/simplefeatures/geom"
+)
+
 // QueryAdvisoriesArgs is the payload passed to query advisories
 // via a POST or GET. Prefer using GET if possible, since it leverages
 // bounding box intersection which has a cheaper computation cost
 // and a faster turnaround time
 type QueryAdvisoriesArgs struct {
 	Geom          geom.Geometry `json:"geometry"`
-	AltitudeUpper float64       `json:"altitudeUpper"`
-	AltitudeLower float64       `json:"altitudeLower"`
+	AltitudeUpper Altitude      `json:"altitudeUpper"`
+	AltitudeLower Altitude      `json:"altitudeLower"`
 	StartTime     time.Time     `json:"startTime"`
 	EndTime       time.Time     `json:"endTime"`
 	GeoIDs        []string      `json:"geoIDs"`
 }
 
-//go:generate enumer -type AdvisoryCategoryType -json -text -transform lower
-type AdvisoryCategoryType byte
+// AdvisoryFilters narrow down a bounding box advisory query. Nil
+// altitudes and zero times are left out of the query, while an
+// altitude of &Altitude{} is sent as 0
+type AdvisoryFilters struct {
+	AltitudeUpper *Altitude
+	AltitudeLower *Altitude
+	StartTime     time.Time
+	EndTime       time.Time
+	GeoIDs        []string
+}
 
-const (
-	Emergency AdvisoryCategoryType = iota + 1
-	Recreational
-	Admin
-)
+// query encodes the filters as query parameters
+func (f *AdvisoryFilters) query() (url.Values, error) {
+	q := url.Values{}
+	if f == nil {
+		return q, nil
+	}
+
+	for name, alt := range map[string]*Altitude{"altitudeLower": f.AltitudeLower, "altitudeUpper": f.AltitudeUpper} {
+		if alt == nil {
+			continue
+		}
+
+		v, err := alt.apiValue()
+		if err != nil {
+			return nil, err
+		}
+
+		q.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+
+	if !f.StartTime.IsZero() {
+		q.Set("startTime", f.StartTime.UTC().Format(time.RFC3339Nano))
+	}
+
+	if !f.EndTime.IsZero() {
+		q.Set("endTime", f.EndTime.UTC().Format(time.RFC3339Nano))
+	}
+
+	if len(f.GeoIDs) > 0 {
+		q.Set("geoIDs", strings.Join(f.GeoIDs, ","))
+	}
+
+	return q, nil
+}
 
 // Advisories represent geographic areas where special consideration must be
 // made before operating drones. Examples of advisories may range from restricted
@@ -33,12 +82,12 @@ type Advisory struct {
 	GeoID            string               `json:"geoID"`
 	AdvisoryCategory AdvisoryCategoryType `json:"advisoryCategory"`
 	Name             string               `json:"name"`
-	Tags             []string             `json:"tags"`
+	Tags             []AdvisoryTag        `json:"tags"`
 
 	// Geospatial/temporal fields
-	AltitudeLower float64       `json:"altitudeLower"`
-	AltitudeUpper float64       `json:"altitudeUpper"`
-	Geometry      geom.Geometry `json:"geometry"` // mark this as unmarshallable for json.RawMessage
+	AltitudeLower Altitude      `json:"altitudeLower"`
+	AltitudeUpper Altitude      `json:"altitudeUpper"`
+	Geometry      geom.Geometry `json:"-"` // carried by the GeoJSON feature, not its properties
 	StartTime     time.Time     `json:"startTime"`
 	EndTime       time.Time     `json:"endTime"`
 	TimezoneName  string        `json:"timezoneName"`
@@ -60,6 +109,10 @@ type Advisory struct {
 	Version int    `json:"version"`
 }
 
+// advisoryProps has Advisory's fields without its JSON methods,
+// so (un)marshalling the feature properties doesn't recurse
+type advisoryProps Advisory
+
 func (a *Advisory) UnmarshalJSON(buf []byte) error {
 	// unmarshal the geometry from the geojson feature
 	// and then capture the properties into the struct:
@@ -79,10 +132,12 @@ func (a *Advisory) UnmarshalJSON(buf []byte) error {
 		return err
 	}
 
-	// now capture properties. Cast to anonymous struct first to avoid
+	// now capture properties. Cast to advisoryProps first to avoid
 	// recursion loop
-	if err := json.Unmarshal(gjFeature.Props, any(a)); err != nil {
-		return err
+	if len(gjFeature.Props) > 0 {
+		if err := json.Unmarshal(gjFeature.Props, (*advisoryProps)(a)); err != nil {
+			return err
+		}
 	}
 
 	// throw geojson geometry on top of it, and we're done
@@ -90,20 +145,64 @@ func (a *Advisory) UnmarshalJSON(buf []byte) error {
 	return nil
 }
 
-func (a *Advisory) MarshalJSON() ([]byte, error) {
+func (a Advisory) MarshalJSON() ([]byte, error) {
+	buf, err := json.Marshal(advisoryProps(a))
+	if err != nil {
+		return nil, err
+	}
+
+	// go through RawMessage so every property keeps its exact encoding
+	var rawProps map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &rawProps); err != nil {
+		return nil, err
+	}
+
+	props := make(map[string]any, len(rawProps))
+	for k, v := range rawProps {
+		props[k] = v
+	}
+
 	return json.Marshal(geom.GeoJSONFeature{
-		Properties: dpm.Params(a).Tag("json").Omit("geometry").FilterZero().Map(),
+		Properties: props,
 		Geometry:   a.Geometry,
 	})
 }
 
-func (a *Client) QueryAdvisoriesByGeom(ctx context.Context, args *QueryAdvisoriesArgs) (*Resp[[]Advisory], error) {
-	req, err := a.makeJSONReq(ctx, "POST", "/v4/advisories", args)
+func (c *Client) QueryAdvisoriesByGeom(ctx context.Context, args *QueryAdvisoriesArgs) (*Resp[[]Advisory], error) {
+	req, err := c.makeJSONReq(ctx, http.MethodPost, "/v4/advisories", args)
 	if err != nil {
 		return nil, err
 	}
+	markIdempotent(req)
 
-	return apiReq[[]Advisory](&a.HTTPClient, req)
+	return authedReq[[]Advisory](c, req)
 }
 
-*/
+// QueryAdvisoriesByBBox fetches the advisories intersecting env via GET,
+// which only does bounding box intersection and so is cheaper and faster
+// than QueryAdvisoriesByGeom. filters may be nil
+func (c *Client) QueryAdvisoriesByBBox(ctx context.Context, env geom.Envelope, filters *AdvisoryFilters) (*Resp[[]Advisory], error) {
+	min, max, ok := env.MinMaxXYs()
+	if !ok {
+		return nil, fmt.Errorf("bounding box is empty")
+	}
+
+	q, err := filters.query()
+	if err != nil {
+		return nil, err
+	}
+
+	q.Set("bbox", fmt.Sprintf("%s,%s,%s,%s",
+		strconv.FormatFloat(min.X, 'f', -1, 64),
+		strconv.FormatFloat(min.Y, 'f', -1, 64),
+		strconv.FormatFloat(max.X, 'f', -1, 64),
+		strconv.FormatFloat(max.Y, 'f', -1, 64),
+	))
+
+	req, err := c.makeReq(ctx, http.MethodGet, "/v4/advisories?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return authedReq[[]Advisory](c, req)
+}
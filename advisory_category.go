@@ -0,0 +1,148 @@
+package asl
+
+import "strings"
+
+// AdvisoryCategoryType is the broad kind of an advisory. Categories the
+// API adds before this list is updated are kept verbatim rather than
+// rejected, so check Known before switching on one
+type AdvisoryCategoryType string
+
+const (
+	Emergency    AdvisoryCategoryType = "emergency"
+	Recreational AdvisoryCategoryType = "recreational"
+	Admin        AdvisoryCategoryType = "admin"
+)
+
+// AdvisoryCategories lists every category this package knows about
+var AdvisoryCategories = []AdvisoryCategoryType{Emergency, Recreational, Admin}
+
+func (c AdvisoryCategoryType) String() string { return string(c) }
+
+// Known reports whether c is one of AdvisoryCategories
+func (c AdvisoryCategoryType) Known() bool {
+	for _, known := range AdvisoryCategories {
+		if c.is(known) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c AdvisoryCategoryType) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+// is compares categories ignoring case, like tags
+func (c AdvisoryCategoryType) is(other AdvisoryCategoryType) bool {
+	return strings.EqualFold(string(c), string(other))
+}
+
+// UnmarshalText accepts any category, normalized to lower case
+func (c *AdvisoryCategoryType) UnmarshalText(text []byte) error {
+	*c = AdvisoryCategoryType(strings.ToLower(string(text)))
+	return nil
+}
+
+// AdvisoryTag describes what an advisory is about in more detail than
+// its category. Tags are kept as sent, including ones not named below,
+// and compared ignoring case, so TagTFR also matches "TFR"
+type AdvisoryTag string
+
+const (
+	TagAirport      AdvisoryTag = "airport"
+	TagHeliport     AdvisoryTag = "heliport"
+	TagSeaplaneBase AdvisoryTag = "seaplane_base"
+	TagStadium      AdvisoryTag = "stadium"
+	TagNationalPark AdvisoryTag = "national_park"
+	TagPrison       AdvisoryTag = "prison"
+	TagPowerPlant   AdvisoryTag = "power_plant"
+	TagHospital     AdvisoryTag = "hospital"
+	TagSchool       AdvisoryTag = "school"
+	TagTFR          AdvisoryTag = "tfr"
+
+	// Special use airspace (SUA) classes
+	TagProhibited       AdvisoryTag = "sua_prohibited"
+	TagRestricted       AdvisoryTag = "sua_restricted"
+	TagWarning          AdvisoryTag = "sua_warning"
+	TagAlert            AdvisoryTag = "sua_alert"
+	TagMOA              AdvisoryTag = "sua_moa"
+	TagNationalSecurity AdvisoryTag = "sua_national_security"
+)
+
+// SUATags are the special use airspace classes
+var SUATags = []AdvisoryTag{TagProhibited, TagRestricted, TagWarning, TagAlert, TagMOA, TagNationalSecurity}
+
+// AdvisoryTags lists every tag this package names
+var AdvisoryTags = append([]AdvisoryTag{
+	TagAirport,
+	TagHeliport,
+	TagSeaplaneBase,
+	TagStadium,
+	TagNationalPark,
+	TagPrison,
+	TagPowerPlant,
+	TagHospital,
+	TagSchool,
+	TagTFR,
+}, SUATags...)
+
+func (t AdvisoryTag) String() string { return string(t) }
+
+// Known reports whether t is one of AdvisoryTags, ignoring case
+func (t AdvisoryTag) Known() bool {
+	for _, known := range AdvisoryTags {
+		if t.is(known) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// is compares tags ignoring case, since sources don't agree on it
+func (t AdvisoryTag) is(other AdvisoryTag) bool {
+	return strings.EqualFold(string(t), string(other))
+}
+
+// HasTag reports whether the advisory carries any of tags, ignoring case
+func (a Advisory) HasTag(tags ...AdvisoryTag) bool {
+	for _, have := range a.Tags {
+		for _, want := range tags {
+			if have.is(want) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// FilterByCategory returns the advisories in any of categories, ignoring case
+func FilterByCategory(advisories []Advisory, categories ...AdvisoryCategoryType) []Advisory {
+	return filterAdvisories(advisories, func(a Advisory) bool {
+		for _, c := range categories {
+			if a.AdvisoryCategory.is(c) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// FilterByTag returns the advisories carrying any of tags, ignoring case
+func FilterByTag(advisories []Advisory, tags ...AdvisoryTag) []Advisory {
+	return filterAdvisories(advisories, func(a Advisory) bool { return a.HasTag(tags...) })
+}
+
+func filterAdvisories(advisories []Advisory, keep func(Advisory) bool) []Advisory {
+	var kept []Advisory
+	for _, a := range advisories {
+		if keep(a) {
+			kept = append(kept, a)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,104 @@
+package asl
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryCategoryJSON(mainTest *testing.T) {
+	testCases := []struct {
+		name          string
+		arg           string
+		expected      AdvisoryCategoryType
+		expectedKnown bool
+	}{
+		{name: "known", arg: `"emergency"`, expected: Emergency, expectedKnown: true},
+		{name: "known, different case", arg: `"ADMIN"`, expected: Admin, expectedKnown: true},
+		{name: "unknown is kept", arg: `"seasonal"`, expected: "seasonal"},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		var actual AdvisoryCategoryType
+		if !t.Nil(json.Unmarshal([]byte(tc.arg), &actual), tc.name) {
+			continue
+		}
+
+		t.Equal(tc.expected, actual, tc.name)
+		t.Equal(tc.expectedKnown, actual.Known(), tc.name)
+
+		buf, err := json.Marshal(actual)
+		if t.Nil(err, tc.name) {
+			t.Equal(`"`+string(tc.expected)+`"`, string(buf), tc.name)
+		}
+	}
+}
+
+func TestAdvisoryFilters(mainTest *testing.T) {
+	advisories := []Advisory{
+		{ID: "airport", AdvisoryCategory: Admin, Tags: []AdvisoryTag{TagAirport}},
+		{ID: "stadium tfr", AdvisoryCategory: Emergency, Tags: []AdvisoryTag{TagStadium, "TFR"}},
+		{ID: "moa", AdvisoryCategory: Admin, Tags: []AdvisoryTag{TagMOA}},
+		{ID: "park", AdvisoryCategory: Recreational, Tags: []AdvisoryTag{TagNationalPark, "dark_sky"}},
+		{ID: "untagged", AdvisoryCategory: "seasonal"},
+	}
+
+	ids := func(advisories []Advisory) []string {
+		var ids []string
+		for _, a := range advisories {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+
+	testCases := []struct {
+		name     string
+		actual   []Advisory
+		expected []string
+	}{
+		{
+			name:     "single category",
+			actual:   FilterByCategory(advisories, Admin),
+			expected: []string{"airport", "moa"},
+		},
+		{
+			name:     "several categories",
+			actual:   FilterByCategory(advisories, Emergency, "seasonal"),
+			expected: []string{"stadium tfr", "untagged"},
+		},
+		{
+			name:     "categories ignore case",
+			actual:   FilterByCategory(advisories, "Emergency", "SEASONAL"),
+			expected: []string{"stadium tfr", "untagged"},
+		},
+		{
+			name:     "tags ignore case",
+			actual:   FilterByTag(advisories, TagTFR),
+			expected: []string{"stadium tfr"},
+		},
+		{
+			name:     "any of several tags",
+			actual:   FilterByTag(advisories, TagMOA, "dark_sky"),
+			expected: []string{"moa", "park"},
+		},
+		{
+			name:     "tag groups",
+			actual:   FilterByTag(advisories, SUATags...),
+			expected: []string{"moa"},
+		},
+		{
+			name:   "no match",
+			actual: FilterByTag(advisories, TagPrison),
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		t.Equal(tc.expected, ids(tc.actual), tc.name)
+	}
+
+	t.True(AdvisoryTag("Sua_Moa").Known())
+	t.False(AdvisoryTag("dark_sky").Known())
+}
@@ -0,0 +1,179 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// The authoring endpoints live under the same /v4/advisories collection
+// as the queries. POST /v4/advisories is already the geometry query, so
+// new advisories are posted to /v4/advisories/create
+const (
+	advisoriesPath     = "/v4/advisories"
+	createAdvisoryPath = advisoriesPath + "/create"
+)
+
+// advisoryPath is the path of a single advisory
+func advisoryPath(id string) (string, error) {
+	if id == "" {
+		return "", fmt.Errorf("advisory ID is required")
+	}
+
+	return advisoriesPath + "/" + url.PathEscape(id), nil
+}
+
+// GetAdvisory fetches a single advisory by ID
+func (c *Client) GetAdvisory(ctx context.Context, id string) (*Resp[Advisory], error) {
+	path, err := advisoryPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.makeReq(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return authedReq[Advisory](c, req)
+}
+
+// CreateAdvisory creates a new, unpublished advisory. The response holds
+// the advisory as stored, including its server assigned ID, OVN and Version
+func (c *Client) CreateAdvisory(ctx context.Context, a *Advisory) (*Resp[Advisory], error) {
+	req, err := c.makeJSONReq(ctx, http.MethodPost, createAdvisoryPath, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return authedReq[Advisory](c, req)
+}
+
+// UpdateAdvisory replaces the advisory with a.ID. If a.OVN or a.Version
+// are set, the update only goes through while they still match the
+// server's; otherwise the error matches ErrConflict. See ModifyAdvisory
+// to handle that
+func (c *Client) UpdateAdvisory(ctx context.Context, a *Advisory) (*Resp[Advisory], error) {
+	path, err := advisoryPath(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.makeJSONReq(ctx, http.MethodPut, versioned(path, a.Version), a)
+	if err != nil {
+		return nil, err
+	}
+
+	conditional(req, a)
+	return authedReq[Advisory](c, req)
+}
+
+// versioned makes a write to path conditional on the advisory
+// still being at version
+func versioned(path string, version int) string {
+	if version == 0 {
+		return path
+	}
+
+	return path + "?version=" + strconv.Itoa(version)
+}
+
+// conditional makes req only go through while the advisory still has
+// a.OVN, and marks it unsafe to retry whenever a.OVN or a.Version make
+// it conditional
+func conditional(req *http.Request, a *Advisory) {
+	if a.OVN != "" {
+		req.Header.Set("If-Match", strconv.Quote(a.OVN))
+	}
+
+	if a.OVN != "" || a.Version != 0 {
+		markConditional(req)
+	}
+}
+
+// modifyAttempts bounds how many times ModifyAdvisory re-applies
+// its mutation after conflicts
+const modifyAttempts = 5
+
+// ModifyAdvisory performs a read-modify-write of the advisory with id:
+// it fetches the advisory, hands it to mutate, and saves the result
+// conditioned on the fetched OVN and Version. When someone else saved in
+// between, the advisory is fetched again and mutate re-applied, so mutate
+// must be safe to call more than once. An error from mutate aborts the
+// update. The conditional save isn't retried on server or network errors,
+// since a save that went through would then look like a conflict and
+// mutate would be applied twice
+func (c *Client) ModifyAdvisory(ctx context.Context, id string, mutate func(*Advisory) error) (*Resp[Advisory], error) {
+	var err error
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		var current *Resp[Advisory]
+		if current, err = c.GetAdvisory(ctx, id); err != nil {
+			return nil, err
+		}
+
+		a := current.Data
+		if err := mutate(&a); err != nil {
+			return nil, err
+		}
+
+		// whatever mutate did, the precondition is what we read
+		a.ID, a.OVN, a.Version = current.Data.ID, current.Data.OVN, current.Data.Version
+
+		var resp *Resp[Advisory]
+		if resp, err = c.UpdateAdvisory(ctx, &a); !errors.Is(err, ErrConflict) {
+			return resp, err
+		}
+	}
+
+	return nil, fmt.Errorf("advisory %s kept changing, gave up after %d attempts: %w", id, modifyAttempts, err)
+}
+
+// PublishAdvisory makes an advisory visible to advisory queries
+func (c *Client) PublishAdvisory(ctx context.Context, id string) (*Resp[Advisory], error) {
+	return c.advisoryAction(ctx, id, "publish")
+}
+
+// UnpublishAdvisory hides an advisory from advisory queries without deleting it
+func (c *Client) UnpublishAdvisory(ctx context.Context, id string) (*Resp[Advisory], error) {
+	return c.advisoryAction(ctx, id, "unpublish")
+}
+
+func (c *Client) advisoryAction(ctx context.Context, id, action string) (*Resp[Advisory], error) {
+	path, err := advisoryPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.makeReq(ctx, http.MethodPost, path+"/"+action, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return authedReq[Advisory](c, req)
+}
+
+// DeleteAdvisory permanently removes an advisory
+func (c *Client) DeleteAdvisory(ctx context.Context, id string) (*Resp[any], error) {
+	return c.DeleteAdvisoryVersion(ctx, &Advisory{ID: id})
+}
+
+// DeleteAdvisoryVersion permanently removes the advisory with a.ID. As
+// with UpdateAdvisory, a set a.OVN and a.Version must still match the
+// server's, otherwise the error matches ErrConflict
+func (c *Client) DeleteAdvisoryVersion(ctx context.Context, a *Advisory) (*Resp[any], error) {
+	path, err := advisoryPath(a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.makeReq(ctx, http.MethodDelete, versioned(path, a.Version), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conditional(req, a)
+	return authedReq[any](c, req)
+}
@@ -0,0 +1,300 @@
+package asl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryCRUD(mainTest *testing.T) {
+	advisory := &Advisory{
+		ID:               "a/1",
+		Name:             "stadium event",
+		AdvisoryCategory: Emergency,
+		Geometry:         exampleGeom1.AsGeometry(),
+	}
+
+	testCases := []struct {
+		name           string
+		call           func(c *Client) (any, error)
+		expectedMethod string
+		expectedPath   string
+		expectedBody   string
+		expectedErr    string
+	}{
+		{
+			name:           "get",
+			call:           func(c *Client) (any, error) { return c.GetAdvisory(context.Background(), "a/1") },
+			expectedMethod: http.MethodGet,
+			expectedPath:   "/v4/advisories/a%2F1",
+		},
+		{
+			name:           "create",
+			call:           func(c *Client) (any, error) { return c.CreateAdvisory(context.Background(), &Advisory{Name: "new"}) },
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/v4/advisories/create",
+			expectedBody:   `"name":"new"`,
+		},
+		{
+			name:           "update",
+			call:           func(c *Client) (any, error) { return c.UpdateAdvisory(context.Background(), advisory) },
+			expectedMethod: http.MethodPut,
+			expectedPath:   "/v4/advisories/a%2F1",
+			expectedBody:   `{"type":"Feature","geometry":{"type":"Polygon"`,
+		},
+		{
+			name:        "update without an ID",
+			call:        func(c *Client) (any, error) { return c.UpdateAdvisory(context.Background(), &Advisory{}) },
+			expectedErr: "advisory ID is required",
+		},
+		{
+			name: "update with an altitude the API can't take",
+			call: func(c *Client) (any, error) {
+				return c.UpdateAdvisory(context.Background(), &Advisory{ID: "a/1", AltitudeUpper: Altitude{Value: 1200, Unit: Feet, Reference: MSL}})
+			},
+			expectedErr: ErrAltitudeReference.Error(),
+		},
+		{
+			name:           "publish",
+			call:           func(c *Client) (any, error) { return c.PublishAdvisory(context.Background(), "a/1") },
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/v4/advisories/a%2F1/publish",
+		},
+		{
+			name:           "unpublish",
+			call:           func(c *Client) (any, error) { return c.UnpublishAdvisory(context.Background(), "a/1") },
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/v4/advisories/a%2F1/unpublish",
+		},
+		{
+			name:           "delete",
+			call:           func(c *Client) (any, error) { return c.DeleteAdvisory(context.Background(), "a/1") },
+			expectedMethod: http.MethodDelete,
+			expectedPath:   "/v4/advisories/a%2F1",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		var actualMethod, actualPath, actualBody string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, _ := io.ReadAll(r.Body)
+			actualMethod, actualPath, actualBody = r.Method, r.URL.EscapedPath(), string(buf)
+
+			w.Write([]byte(`{"statusCode":200,"data":{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"id":"a/1","published":true,"version":3}}}`))
+		}))
+
+		actual, actualErr := tc.call(&Client{BaseURL: srv.URL})
+		srv.Close()
+
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		if !t.Nil(actualErr, tc.name) {
+			continue
+		}
+
+		t.Equal(tc.expectedMethod, actualMethod, tc.name)
+		t.Equal(tc.expectedPath, actualPath, tc.name)
+		t.Contains(actualBody, tc.expectedBody, tc.name)
+
+		if resp, ok := actual.(*Resp[Advisory]); ok {
+			t.Equal("a/1", resp.Data.ID, tc.name)
+			t.Equal(3, resp.Data.Version, tc.name)
+			t.True(resp.Data.Geometry.IsPoint(), tc.name)
+		}
+	}
+}
+
+// advisoryStore mocks the single advisory endpoints with OVN preconditions.
+// The first interference GETs are each followed by someone else's write
+type advisoryStore struct {
+	mu           sync.Mutex
+	name         string
+	version      int
+	interference int
+	puts         int
+}
+
+func (s *advisoryStore) ovn() string { return fmt.Sprintf("ovn-%d", s.version) }
+
+func (s *advisoryStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	respond := func() {
+		fmt.Fprintf(w, `{"statusCode":200,"data":{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"id":"a1","name":%q,"ovn":%q,"version":%d}}}`, s.name, s.ovn(), s.version)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		respond()
+		if s.interference > 0 {
+			s.interference--
+			s.version++
+		}
+	case http.MethodPut, http.MethodDelete:
+		s.puts++
+		match, version := r.Header.Get("If-Match"), r.URL.Query().Get("version")
+		if (match != "" && match != strconv.Quote(s.ovn())) || (version != "" && version != strconv.Itoa(s.version)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte(`{"message":"advisory has changed"}`))
+			return
+		}
+
+		var a Advisory
+		json.NewDecoder(r.Body).Decode(&a)
+		s.name = a.Name
+		s.version++
+		respond()
+	}
+}
+
+func TestAdvisoryPreconditions(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		call        func(c *Client) (any, error)
+		expectedErr error
+	}{
+		{
+			name: "update with the current OVN",
+			call: func(c *Client) (any, error) {
+				return c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1", OVN: "ovn-1", Version: 1})
+			},
+		},
+		{
+			name: "update with a stale OVN",
+			call: func(c *Client) (any, error) {
+				return c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1", OVN: "ovn-0", Version: 0})
+			},
+			expectedErr: ErrConflict,
+		},
+		{
+			name: "unconditional update",
+			call: func(c *Client) (any, error) {
+				return c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1"})
+			},
+		},
+		{
+			name: "update with a stale version",
+			call: func(c *Client) (any, error) {
+				return c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1", Version: 5})
+			},
+			expectedErr: ErrConflict,
+		},
+		{
+			name: "delete with a stale OVN",
+			call: func(c *Client) (any, error) {
+				return c.DeleteAdvisoryVersion(context.Background(), &Advisory{ID: "a1", OVN: "ovn-0"})
+			},
+			expectedErr: ErrConflict,
+		},
+		{
+			name: "delete with a stale version",
+			call: func(c *Client) (any, error) {
+				return c.DeleteAdvisoryVersion(context.Background(), &Advisory{ID: "a1", Version: 5})
+			},
+			expectedErr: ErrConflict,
+		},
+		{
+			name: "unconditional delete",
+			call: func(c *Client) (any, error) {
+				return c.DeleteAdvisory(context.Background(), "a1")
+			},
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		srv := httptest.NewServer(&advisoryStore{version: 1})
+		_, actualErr := tc.call(&Client{BaseURL: srv.URL})
+		srv.Close()
+
+		if tc.expectedErr == nil {
+			t.Nil(actualErr, tc.name)
+			continue
+		}
+
+		t.True(errors.Is(actualErr, tc.expectedErr), tc.name)
+	}
+}
+
+func TestModifyAdvisory(mainTest *testing.T) {
+	testCases := []struct {
+		name            string
+		interference    int
+		mutateErr       error
+		expectedPuts    int
+		expectedCalls   int
+		expectedVersion int
+		expectedErr     error
+	}{
+		{
+			name:            "no conflict",
+			expectedPuts:    1,
+			expectedCalls:   1,
+			expectedVersion: 2,
+		},
+		{
+			name:            "conflicts are retried with a fresh read",
+			interference:    2,
+			expectedPuts:    3,
+			expectedCalls:   3,
+			expectedVersion: 4,
+		},
+		{
+			name:          "gives up eventually",
+			interference:  modifyAttempts,
+			expectedPuts:  modifyAttempts,
+			expectedCalls: modifyAttempts,
+			expectedErr:   ErrConflict,
+		},
+		{
+			name:          "mutate errors abort",
+			mutateErr:     errors.New("nope"),
+			expectedCalls: 1,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		store := &advisoryStore{name: "before", version: 1, interference: tc.interference}
+		srv := httptest.NewServer(store)
+
+		calls := 0
+		resp, actualErr := (&Client{BaseURL: srv.URL}).ModifyAdvisory(context.Background(), "a1", func(a *Advisory) error {
+			calls++
+			a.Name = "after"
+			return tc.mutateErr
+		})
+		srv.Close()
+
+		t.Equal(tc.expectedCalls, calls, tc.name)
+		t.Equal(tc.expectedPuts, store.puts, tc.name)
+
+		switch {
+		case tc.mutateErr != nil:
+			t.Equal(tc.mutateErr, actualErr, tc.name)
+		case tc.expectedErr != nil:
+			t.True(errors.Is(actualErr, tc.expectedErr), tc.name)
+		default:
+			if t.Nil(actualErr, tc.name) {
+				t.Equal("after", resp.Data.Name, tc.name)
+				t.Equal(tc.expectedVersion, resp.Data.Version, tc.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package asl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// advisoryCollection is a GeoJSON FeatureCollection of advisories
+type advisoryCollection struct {
+	Type     string     `json:"type"`
+	Features []Advisory `json:"features"`
+}
+
+// MarshalFeatureCollection encodes advisories as a GeoJSON FeatureCollection,
+// each advisory being a Feature like the one Advisory.MarshalJSON emits
+func MarshalFeatureCollection(advisories []Advisory) ([]byte, error) {
+	if advisories == nil {
+		advisories = []Advisory{}
+	}
+
+	return json.Marshal(advisoryCollection{Type: "FeatureCollection", Features: advisories})
+}
+
+// UnmarshalFeatureCollection decodes the advisories in a GeoJSON FeatureCollection
+func UnmarshalFeatureCollection(buf []byte) ([]Advisory, error) {
+	var fc advisoryCollection
+	if err := json.Unmarshal(buf, &fc); err != nil {
+		return nil, err
+	}
+
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("expected a GeoJSON FeatureCollection, got type %q", fc.Type)
+	}
+
+	return fc.Features, nil
+}
+
+// AdvisoryWriter writes advisories as newline-delimited GeoJSON,
+// one Feature per line
+type AdvisoryWriter struct {
+	enc *json.Encoder
+}
+
+func NewAdvisoryWriter(w io.Writer) *AdvisoryWriter {
+	return &AdvisoryWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes a as a single line
+func (w *AdvisoryWriter) Write(a Advisory) error {
+	return w.enc.Encode(a)
+}
+
+// AdvisoryReader streams advisories out of newline-delimited GeoJSON.
+// Use it like AdvisoryIterator:
+//
+//	r := asl.NewAdvisoryReader(f)
+//	for r.Next() {
+//		a := r.Advisory()
+//		...
+//	}
+//	if err := r.Err(); err != nil {
+//		...
+//	}
+type AdvisoryReader struct {
+	dec     *json.Decoder
+	current Advisory
+	err     error
+	count   int
+}
+
+func NewAdvisoryReader(r io.Reader) *AdvisoryReader {
+	return &AdvisoryReader{dec: json.NewDecoder(r)}
+}
+
+// Next decodes the next advisory. It returns false at the
+// end of the input or on the first malformed feature
+func (r *AdvisoryReader) Next() bool {
+	if r.err != nil || !r.dec.More() {
+		return false
+	}
+
+	r.count++
+
+	var a Advisory
+	if err := r.dec.Decode(&a); err != nil {
+		r.err = fmt.Errorf("decoding advisory %d: %w", r.count, err)
+		return false
+	}
+
+	r.current = a
+	return true
+}
+
+// Advisory returns the advisory decoded by the last call to Next
+func (r *AdvisoryReader) Advisory() Advisory { return r.current }
+
+// Err returns the error that stopped the reader, if any
+func (r *AdvisoryReader) Err() error { return r.err }
@@ -0,0 +1,95 @@
+package asl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func geojsonAdvisories() []Advisory {
+	email, empty := "ops@example.com", ""
+	return []Advisory{
+		{
+			ID:               "a1",
+			AdvisoryCategory: Emergency,
+			Name:             "Fire & rescue <TFR>",
+			Tags:             []AdvisoryTag{TagTFR},
+			Geometry:         exampleGeom1.AsGeometry(),
+			AltitudeUpper:    FeetAGL(400),
+			StartTime:        time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC),
+			ContactEmail:     &email,
+			ContactPhone:     &empty,
+			Published:        true,
+			OVN:              "ovn1",
+			Version:          3,
+		},
+		{
+			ID:       "a2",
+			Geometry: examplePoint.AsGeometry(),
+		},
+	}
+}
+
+func TestFeatureCollection(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	buf, err := MarshalFeatureCollection(geojsonAdvisories())
+	t.NoError(err)
+	t.True(strings.HasPrefix(string(buf), `{"type":"FeatureCollection","features":[{"type":"Feature"`))
+	t.Contains(string(buf), `"contactEmail":"ops@example.com","contactPhone":""`)
+	t.Contains(string(buf), `"contactEmail":null,"contactPhone":null`)
+
+	advisories, err := UnmarshalFeatureCollection(buf)
+	t.NoError(err)
+
+	again, err := MarshalFeatureCollection(advisories)
+	t.NoError(err)
+	t.JSONEq(string(buf), string(again))
+
+	empty, err := MarshalFeatureCollection(nil)
+	t.NoError(err)
+	t.Equal(`{"type":"FeatureCollection","features":[]}`, string(empty))
+
+	_, err = UnmarshalFeatureCollection([]byte(`{"type":"Feature","geometry":null,"properties":{}}`))
+	t.EqualError(err, `expected a GeoJSON FeatureCollection, got type "Feature"`)
+}
+
+func TestAdvisoryNDJSON(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	var buf bytes.Buffer
+	w := NewAdvisoryWriter(&buf)
+	for _, a := range geojsonAdvisories() {
+		t.NoError(w.Write(a))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	t.Len(lines, 2)
+
+	var read []Advisory
+	r := NewAdvisoryReader(strings.NewReader(buf.String() + "\n"))
+	for r.Next() {
+		read = append(read, r.Advisory())
+	}
+
+	t.NoError(r.Err())
+	t.Len(read, 2)
+
+	for i, a := range geojsonAdvisories() {
+		expected, err := a.MarshalJSON()
+		t.NoError(err)
+
+		actual, err := read[i].MarshalJSON()
+		t.NoError(err)
+		t.JSONEq(string(expected), string(actual))
+	}
+
+	r = NewAdvisoryReader(strings.NewReader(lines[0] + "\n{\"type\":\"Feature\",\"properties\":{\"id\":7}}\n" + lines[1]))
+	t.True(r.Next())
+	t.Equal("a1", r.Advisory().ID)
+	t.False(r.Next())
+	t.Contains(r.Err().Error(), "decoding advisory 2")
+}
@@ -0,0 +1,225 @@
+package asl
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/peterstace/simplefeatures/geom"
+	"github.com/peterstace/simplefeatures/rtree"
+)
+
+// AdvisoryIndex is an in-memory R-tree of advisories for answering
+// "which advisories cover this?" without going back to the API. It can
+// be updated advisory by advisory and is safe for concurrent use. The
+// zero value is an empty index
+type AdvisoryIndex struct {
+	mu      sync.RWMutex
+	tree    rtree.RTree
+	records map[int]indexedAdvisory
+	ids     map[string]int
+	nextID  int
+}
+
+type indexedAdvisory struct {
+	advisory Advisory
+	box      rtree.Box
+
+	// advisories with empty geometries are kept, but can't be found spatially
+	indexed bool
+}
+
+// envelopeBox converts an envelope to an R-tree box
+func envelopeBox(env geom.Envelope) (rtree.Box, bool) {
+	min, max, ok := env.MinMaxXYs()
+	return rtree.Box{MinX: min.X, MinY: min.Y, MaxX: max.X, MaxY: max.Y}, ok
+}
+
+// NewAdvisoryIndex bulk loads advisories into a new index. Later
+// advisories replace earlier ones with the same ID
+func NewAdvisoryIndex(advisories []Advisory) *AdvisoryIndex {
+	idx := &AdvisoryIndex{
+		records: make(map[int]indexedAdvisory, len(advisories)),
+		ids:     make(map[string]int, len(advisories)),
+	}
+
+	for _, a := range advisories {
+		if recordID, ok := idx.ids[a.ID]; ok {
+			delete(idx.records, recordID)
+		}
+
+		idx.ids[a.ID] = idx.add(a)
+	}
+
+	items := make([]rtree.BulkItem, 0, len(idx.records))
+	for recordID, rec := range idx.records {
+		if rec.indexed {
+			items = append(items, rtree.BulkItem{Box: rec.box, RecordID: recordID})
+		}
+	}
+
+	idx.tree = *rtree.BulkLoad(items)
+	return idx
+}
+
+// add records a under a new record ID without touching the tree
+func (idx *AdvisoryIndex) add(a Advisory) int {
+	if idx.records == nil {
+		idx.records = make(map[int]indexedAdvisory)
+		idx.ids = make(map[string]int)
+	}
+
+	rec := indexedAdvisory{advisory: a}
+	rec.box, rec.indexed = envelopeBox(a.Geometry.Envelope())
+
+	recordID := idx.nextID
+	idx.nextID++
+	idx.records[recordID] = rec
+	return recordID
+}
+
+// Len returns how many advisories are indexed
+func (idx *AdvisoryIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.records)
+}
+
+// Get returns the indexed advisory with id
+func (idx *AdvisoryIndex) Get(id string) (Advisory, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	recordID, ok := idx.ids[id]
+	if !ok {
+		return Advisory{}, false
+	}
+
+	return idx.records[recordID].advisory, true
+}
+
+// Upsert adds a, replacing any indexed advisory with the same ID
+func (idx *AdvisoryIndex) Upsert(a Advisory) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(a.ID)
+	recordID := idx.add(a)
+	idx.ids[a.ID] = recordID
+
+	if rec := idx.records[recordID]; rec.indexed {
+		idx.tree.Insert(rec.box, recordID)
+	}
+}
+
+// Remove drops the advisory with id, reporting whether it was indexed
+func (idx *AdvisoryIndex) Remove(id string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.remove(id)
+}
+
+func (idx *AdvisoryIndex) remove(id string) bool {
+	recordID, ok := idx.ids[id]
+	if !ok {
+		return false
+	}
+
+	if rec := idx.records[recordID]; rec.indexed {
+		idx.tree.Delete(rec.box, recordID)
+	}
+
+	delete(idx.records, recordID)
+	delete(idx.ids, id)
+	return true
+}
+
+// QueryPoint returns the advisories covering p. filters may be nil
+func (idx *AdvisoryIndex) QueryPoint(p geom.XY, filters *AdvisoryFilters) ([]Advisory, error) {
+	pt, err := geom.NewPoint(geom.Coordinates{XY: p})
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.QueryGeometry(pt.AsGeometry(), filters)
+}
+
+// QueryEnvelope returns the advisories intersecting env. filters may be nil
+func (idx *AdvisoryIndex) QueryEnvelope(env geom.Envelope, filters *AdvisoryFilters) ([]Advisory, error) {
+	return idx.QueryGeometry(env.AsGeometry(), filters)
+}
+
+// QueryGeometry returns the advisories intersecting g, sorted by ID.
+// filters may be nil; otherwise advisories must also overlap its
+// altitude band and time window and, if it lists any, have one of its
+// GeoIDs. Altitudes in another datum than the filter's are an error
+// matching ErrAltitudeReference
+func (idx *AdvisoryIndex) QueryGeometry(g geom.Geometry, filters *AdvisoryFilters) ([]Advisory, error) {
+	box, ok := envelopeBox(g.Envelope())
+	if !ok {
+		return nil, nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var found []Advisory
+	err := idx.tree.RangeSearch(box, func(recordID int) error {
+		a := idx.records[recordID].advisory
+
+		keep, err := filters.match(a)
+		if err != nil || !keep {
+			return err
+		}
+
+		if geom.Intersects(g, a.Geometry) {
+			found = append(found, a)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found, nil
+}
+
+// match reports whether a passes the non-spatial filters
+func (f *AdvisoryFilters) match(a Advisory) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+
+	if len(f.GeoIDs) > 0 {
+		matched := false
+		for _, id := range f.GeoIDs {
+			matched = matched || id == a.GeoID
+		}
+
+		if !matched {
+			return false, nil
+		}
+	}
+
+	if _, _, ok := overlapTime(f.StartTime, f.EndTime, a.StartTime, a.EndTime); !ok {
+		return false, nil
+	}
+
+	if f.AltitudeLower == nil && f.AltitudeUpper == nil {
+		return true, nil
+	}
+
+	var lower, upper Altitude
+	if f.AltitudeLower != nil {
+		lower = *f.AltitudeLower
+	}
+
+	if f.AltitudeUpper != nil {
+		upper = *f.AltitudeUpper
+	}
+
+	_, _, ok, err := overlapAltitude(lower, upper, a.AltitudeLower, a.AltitudeUpper)
+	return ok, err
+}
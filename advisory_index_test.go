@@ -0,0 +1,188 @@
+package asl
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/peterstace/simplefeatures/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func advisoryIDs(advisories []Advisory) []string {
+	ids := make([]string, 0, len(advisories))
+	for _, a := range advisories {
+		ids = append(ids, a.ID)
+	}
+
+	return ids
+}
+
+func mustEnvelope(minX, minY, maxX, maxY float64) geom.Envelope {
+	env, err := geom.NewEnvelope([]geom.XY{{X: minX, Y: minY}, {X: maxX, Y: maxY}})
+	if err != nil {
+		panic(err)
+	}
+
+	return env
+}
+
+func TestAdvisoryIndexQuery(mainTest *testing.T) {
+	noon := time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC)
+	idx := NewAdvisoryIndex([]Advisory{
+		{
+			ID:            "low",
+			GeoID:         "US",
+			Geometry:      mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+			AltitudeUpper: FeetAGL(400),
+		},
+		{
+			ID:            "high",
+			GeoID:         "US",
+			Geometry:      mustWKT("POLYGON((1 1,3 1,3 3,1 3,1 1))"),
+			AltitudeLower: FeetAGL(1000),
+			StartTime:     noon,
+			EndTime:       noon.Add(time.Hour),
+		},
+		{
+			ID:       "diagonal",
+			GeoID:    "CA",
+			Geometry: mustWKT("LINESTRING(5 5,7 7)"),
+		},
+		{
+			ID:       "missing",
+			Geometry: geom.Geometry{},
+		},
+	})
+
+	testCases := []struct {
+		name     string
+		query    func() ([]Advisory, error)
+		expected []string
+		err      error
+	}{
+		{
+			name:     "point in two advisories",
+			query:    func() ([]Advisory, error) { return idx.QueryPoint(geom.XY{X: 1.5, Y: 1.5}, nil) },
+			expected: []string{"high", "low"},
+		},
+		{
+			name:     "point in nothing",
+			query:    func() ([]Advisory, error) { return idx.QueryPoint(geom.XY{X: 10, Y: 10}, nil) },
+			expected: []string{},
+		},
+		{
+			name: "envelope over the line's bounding box but not the line",
+			query: func() ([]Advisory, error) {
+				return idx.QueryEnvelope(mustEnvelope(6.5, 5, 7, 5.5), nil)
+			},
+			expected: []string{},
+		},
+		{
+			name: "envelope across everything",
+			query: func() ([]Advisory, error) {
+				return idx.QueryEnvelope(mustEnvelope(-1, -1, 10, 10), nil)
+			},
+			expected: []string{"diagonal", "high", "low"},
+		},
+		{
+			name: "geometry with an altitude band",
+			query: func() ([]Advisory, error) {
+				return idx.QueryGeometry(mustWKT("LINESTRING(0.5 0.5,2.5 2.5)"), &AdvisoryFilters{
+					AltitudeLower: &Altitude{},
+					AltitudeUpper: &Altitude{Value: 200, Unit: Feet, Reference: AGL},
+				})
+			},
+			expected: []string{"low"},
+		},
+		{
+			name: "geometry with a time window",
+			query: func() ([]Advisory, error) {
+				return idx.QueryGeometry(mustWKT("LINESTRING(0.5 0.5,2.5 2.5)"), &AdvisoryFilters{
+					StartTime: noon.Add(2 * time.Hour),
+					EndTime:   noon.Add(3 * time.Hour),
+				})
+			},
+			expected: []string{"low"},
+		},
+		{
+			name: "geo IDs",
+			query: func() ([]Advisory, error) {
+				return idx.QueryEnvelope(mustEnvelope(-1, -1, 10, 10), &AdvisoryFilters{
+					GeoIDs: []string{"CA"},
+				})
+			},
+			expected: []string{"diagonal"},
+		},
+		{
+			name: "altitude in another datum",
+			query: func() ([]Advisory, error) {
+				return idx.QueryPoint(geom.XY{X: 0.5, Y: 0.5}, &AdvisoryFilters{
+					AltitudeUpper: &Altitude{Value: 400, Unit: Feet, Reference: MSL},
+				})
+			},
+			err: ErrAltitudeReference,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		found, err := tc.query()
+		if tc.err != nil {
+			t.True(errors.Is(err, tc.err), tc.name)
+			continue
+		}
+
+		t.NoError(err, tc.name)
+		t.Equal(tc.expected, advisoryIDs(found), tc.name)
+	}
+}
+
+func TestAdvisoryIndexUpdate(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	idx := NewAdvisoryIndex([]Advisory{
+		{ID: "a", Geometry: mustWKT("POLYGON((0 0,1 0,1 1,0 1,0 0))")},
+		{ID: "b", Geometry: mustWKT("POLYGON((0 0,1 0,1 1,0 1,0 0))")},
+		{ID: "a", Geometry: mustWKT("POLYGON((5 5,6 5,6 6,5 6,5 5))"), Version: 2},
+	})
+
+	t.Equal(2, idx.Len())
+
+	a, ok := idx.Get("a")
+	t.True(ok)
+	t.Equal(2, a.Version)
+
+	found, err := idx.QueryPoint(geom.XY{X: 0.5, Y: 0.5}, nil)
+	t.NoError(err)
+	t.Equal([]string{"b"}, advisoryIDs(found))
+
+	// moving an advisory drops it from where it was
+	idx.Upsert(Advisory{ID: "b", Geometry: mustWKT("POLYGON((5 5,6 5,6 6,5 6,5 5))"), Version: 3})
+
+	found, err = idx.QueryPoint(geom.XY{X: 0.5, Y: 0.5}, nil)
+	t.NoError(err)
+	t.Equal([]string{}, advisoryIDs(found))
+
+	found, err = idx.QueryPoint(geom.XY{X: 5.5, Y: 5.5}, nil)
+	t.NoError(err)
+	t.Equal([]string{"a", "b"}, advisoryIDs(found))
+
+	t.True(idx.Remove("a"))
+	t.False(idx.Remove("a"))
+
+	_, ok = idx.Get("a")
+	t.False(ok)
+
+	found, err = idx.QueryPoint(geom.XY{X: 5.5, Y: 5.5}, nil)
+	t.NoError(err)
+	t.Equal([]string{"b"}, advisoryIDs(found))
+	t.Equal(1, idx.Len())
+
+	var empty AdvisoryIndex
+	empty.Upsert(Advisory{ID: "c", Geometry: mustWKT("POINT(1 1)")})
+
+	found, err = empty.QueryEnvelope(mustEnvelope(0, 0, 2, 2), nil)
+	t.NoError(err)
+	t.Equal([]string{"c"}, advisoryIDs(found))
+}
@@ -0,0 +1,256 @@
+package asl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DefaultAdvisoryPageSize is how many advisories an AdvisoryIterator
+// asks for per page when no page size is given
+const DefaultAdvisoryPageSize = 500
+
+// AdvisoryIterator walks the results of an advisory query page by page,
+// decoding one feature at a time straight off the response body so a
+// statewide query never has to fit in memory. Use it like bufio.Scanner:
+//
+//	it := client.IterateAdvisories(ctx, args, 0)
+//	defer it.Close()
+//	for it.Next() {
+//		a := it.Advisory()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// The API reference doesn't document paging, so the iterator assumes
+// as little as it can. Each page asks for limit results, plus the
+// nextCursor the previous page returned or, without one, an offset
+// past the results read so far. It then copes with a server that
+// ignores any of these. Without a cursor, a short or oversized page is
+// taken to be the last one. A page that starts with the same advisory
+// as the page before it, or repeats the cursor it was asked for, ends
+// the iteration instead of looping forever
+type AdvisoryIterator struct {
+	client   *Client
+	ctx      context.Context
+	args     *QueryAdvisoriesArgs
+	pageSize int
+
+	// position of the next page
+	cursor string
+	offset int
+
+	// the page being read
+	body       io.ReadCloser
+	dec        *json.Decoder
+	inData     bool
+	pageCount  int
+	nextCursor string
+
+	// first advisory IDs of the current and previous pages, to
+	// spot a server that keeps sending the same page
+	firstID     string
+	prevFirstID string
+
+	current Advisory
+	err     error
+	done    bool
+}
+
+// IterateAdvisories queries advisories like QueryAdvisoriesByGeom, but
+// pages through the results. A pageSize of zero uses DefaultAdvisoryPageSize
+func (c *Client) IterateAdvisories(ctx context.Context, args *QueryAdvisoriesArgs, pageSize int) *AdvisoryIterator {
+	if pageSize <= 0 {
+		pageSize = DefaultAdvisoryPageSize
+	}
+
+	return &AdvisoryIterator{client: c, ctx: ctx, args: args, pageSize: pageSize}
+}
+
+// Next decodes the next advisory, fetching the next page when the
+// current one runs out. It returns false once the results are
+// exhausted or an error occurs
+func (it *AdvisoryIterator) Next() bool {
+	for !it.done {
+		if it.body == nil {
+			if it.err = it.openPage(); it.err != nil {
+				it.Close()
+				return false
+			}
+		}
+
+		if it.inData && it.dec.More() {
+			var a Advisory
+			if it.err = it.dec.Decode(&a); it.err != nil {
+				it.Close()
+				return false
+			}
+
+			if it.pageCount == 0 {
+				if a.ID != "" && a.ID == it.prevFirstID {
+					it.Close()
+					return false
+				}
+
+				it.firstID = a.ID
+			}
+
+			it.pageCount++
+			it.current = a
+			return true
+		}
+
+		if it.err = it.finishPage(); it.err != nil {
+			it.Close()
+			return false
+		}
+	}
+
+	return false
+}
+
+// Advisory returns the advisory decoded by the last call to Next
+func (it *AdvisoryIterator) Advisory() Advisory { return it.current }
+
+// Err returns the error that stopped the iteration, if any
+func (it *AdvisoryIterator) Err() error { return it.err }
+
+// Close releases the page being read. It's safe to call more than once
+func (it *AdvisoryIterator) Close() error {
+	it.done = true
+	if it.body == nil {
+		return nil
+	}
+
+	err := it.body.Close()
+	it.body = nil
+	return err
+}
+
+// openPage requests the next page and reads up to the start of its data array
+func (it *AdvisoryIterator) openPage() error {
+	q := url.Values{"limit": {strconv.Itoa(it.pageSize)}}
+	if it.cursor != "" {
+		q.Set("cursor", it.cursor)
+	} else if it.offset > 0 {
+		q.Set("offset", strconv.Itoa(it.offset))
+	}
+
+	req, err := it.client.makeJSONReq(it.ctx, http.MethodPost, "/v4/advisories?"+q.Encode(), it.args)
+	if err != nil {
+		return err
+	}
+	markIdempotent(req)
+
+	resp, err := it.client.authedDo(req)
+	if err != nil {
+		return err
+	}
+
+	it.body, it.dec = resp.Body, json.NewDecoder(resp.Body)
+	it.inData, it.pageCount, it.nextCursor, it.firstID = false, 0, "", ""
+
+	if err := expectDelim(it.dec, '{'); err != nil {
+		return err
+	}
+
+	return it.readFields(true)
+}
+
+// finishPage reads the rest of the current page and works out where the
+// next one starts, if there is one
+func (it *AdvisoryIterator) finishPage() error {
+	if it.inData {
+		if err := expectDelim(it.dec, ']'); err != nil {
+			return err
+		}
+
+		it.inData = false
+		if err := it.readFields(false); err != nil {
+			return err
+		}
+	}
+
+	if err := it.body.Close(); err != nil {
+		return err
+	}
+	it.body = nil
+
+	it.prevFirstID = it.firstID
+	switch {
+	case it.pageCount == 0:
+		it.done = true
+	case it.nextCursor != "" && it.nextCursor != it.cursor:
+		it.cursor = it.nextCursor
+	case it.cursor == "" && it.pageCount == it.pageSize:
+		it.offset += it.pageCount
+	default:
+		it.done = true
+	}
+
+	return nil
+}
+
+// readFields walks the keys of the response envelope, remembering the
+// next cursor. With untilData, it stops once inside the data array;
+// otherwise it reads through the end of the envelope
+func (it *AdvisoryIterator) readFields(untilData bool) error {
+	for it.dec.More() {
+		tok, err := it.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key, _ := tok.(string); {
+		case key == "nextCursor":
+			var cursor *string
+			if err := it.dec.Decode(&cursor); err != nil {
+				return err
+			}
+
+			if cursor != nil {
+				it.nextCursor = *cursor
+			}
+		case key == "data" && untilData:
+			tok, err := it.dec.Token()
+			if err != nil {
+				return err
+			}
+
+			if tok == json.Delim('[') {
+				it.inData = true
+				return nil
+			}
+
+			if tok != nil {
+				return fmt.Errorf("expected advisory data to be an array, got %v", tok)
+			}
+		default:
+			var skip json.RawMessage
+			if err := it.dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+
+	return expectDelim(it.dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if tok != delim {
+		return fmt.Errorf("expected %v in advisory response, got %v", delim, tok)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package asl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pagedAdvisories mocks the advisory query with total results, paging by
+// cursor (placed before or after the data) or by offset, or ignoring
+// the offset or always sending back the first cursor
+func pagedAdvisories(total int, paging string, failPage int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		start, _ := strconv.Atoi(q.Get("offset"))
+		if cursor := q.Get("cursor"); cursor != "" {
+			start, _ = strconv.Atoi(strings.TrimPrefix(cursor, "c"))
+		}
+
+		if paging == "offset ignored" {
+			start = 0
+		}
+
+		if failPage > 0 && start/limit+1 == failPage {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+			return
+		}
+
+		features := []string{}
+		for i := start; i < total && i < start+limit; i++ {
+			features = append(features, fmt.Sprintf(`{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"id":"a%d"}}`, i))
+		}
+
+		data := "[" + strings.Join(features, ",") + "]"
+		next := "null"
+		if start+limit < total {
+			next = fmt.Sprintf(`"c%d"`, start+limit)
+		}
+
+		if paging == "cursor stuck" {
+			next = `"c0"`
+		}
+
+		switch paging {
+		case "cursor after data", "cursor stuck":
+			fmt.Fprintf(w, `{"statusCode":200,"data":%s,"nextCursor":%s,"message":"ok"}`, data, next)
+		case "cursor before data":
+			fmt.Fprintf(w, `{"statusCode":200,"nextCursor":%s,"meta":{"nested":[1,{"a":2}]},"data":%s}`, next, data)
+		case "offset", "offset ignored":
+			fmt.Fprintf(w, `{"statusCode":200,"data":%s}`, data)
+		case "null data":
+			w.Write([]byte(`{"statusCode":200,"data":null}`))
+		}
+	})
+}
+
+func TestAdvisoryIterator(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		total       int
+		paging      string
+		failPage    int
+		expectedIDs int
+		expectedErr string
+	}{
+		{
+			name:        "cursor after the data",
+			total:       5,
+			paging:      "cursor after data",
+			expectedIDs: 5,
+		},
+		{
+			name:        "cursor before the data",
+			total:       5,
+			paging:      "cursor before data",
+			expectedIDs: 5,
+		},
+		{
+			name:        "offset paging",
+			total:       5,
+			paging:      "offset",
+			expectedIDs: 5,
+		},
+		{
+			name:        "offset paging ending on a full page",
+			total:       4,
+			paging:      "offset",
+			expectedIDs: 4,
+		},
+		{
+			name:        "server ignoring the offset",
+			total:       5,
+			paging:      "offset ignored",
+			expectedIDs: 2,
+		},
+		{
+			name:        "server repeating the cursor",
+			total:       5,
+			paging:      "cursor stuck",
+			expectedIDs: 2,
+		},
+		{
+			name:        "no results",
+			paging:      "cursor after data",
+			expectedIDs: 0,
+		},
+		{
+			name:        "null data",
+			paging:      "null data",
+			expectedIDs: 0,
+		},
+		{
+			name:        "error on a later page",
+			total:       5,
+			paging:      "cursor after data",
+			failPage:    2,
+			expectedIDs: 2,
+			expectedErr: "502 <html>bad gateway</html>",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		srv := httptest.NewServer(pagedAdvisories(tc.total, tc.paging, tc.failPage))
+		client := &Client{BaseURL: srv.URL}
+
+		it := client.IterateAdvisories(context.Background(), &QueryAdvisoriesArgs{}, 2)
+		var ids []string
+		for it.Next() {
+			ids = append(ids, it.Advisory().ID)
+		}
+		it.Close()
+		srv.Close()
+
+		t.Len(ids, tc.expectedIDs, tc.name)
+		for i, id := range ids {
+			t.Equal(fmt.Sprintf("a%d", i), id, tc.name)
+		}
+
+		if tc.expectedErr != "" {
+			if t.Error(it.Err(), tc.name) {
+				t.Contains(it.Err().Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		t.Nil(it.Err(), tc.name)
+	}
+}
@@ -0,0 +1,53 @@
+package asl
+
+import (
+	"fmt"
+	"time"
+)
+
+// Location loads the advisory's timezone. Advisories without
+// a TimezoneName are treated as UTC
+func (a Advisory) Location() (*time.Location, error) {
+	if a.TimezoneName == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(a.TimezoneName)
+	if err != nil {
+		return nil, fmt.Errorf("advisory %s has unknown timezone %q: %w", a.ID, a.TimezoneName, err)
+	}
+
+	return loc, nil
+}
+
+// LocalWindow returns StartTime and EndTime in the advisory's timezone.
+// Zero times, meaning unbounded, stay zero
+func (a Advisory) LocalWindow() (time.Time, time.Time, error) {
+	loc, err := a.Location()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	start, end := a.StartTime, a.EndTime
+	if !start.IsZero() {
+		start = start.In(loc)
+	}
+
+	if !end.IsZero() {
+		end = end.In(loc)
+	}
+
+	return start, end, nil
+}
+
+// ActiveAt reports whether the advisory is in effect at t, within
+// [StartTime, EndTime). A zero StartTime or EndTime leaves that side
+// unbounded, so an advisory with neither is always active. Otherwise an
+// EndTime at or before StartTime is an empty window, never active
+func (a Advisory) ActiveAt(t time.Time) bool {
+	if !a.StartTime.IsZero() && t.Before(a.StartTime) {
+		return false
+	}
+
+	return a.EndTime.IsZero() || t.Before(a.EndTime)
+}
@@ -0,0 +1,96 @@
+package asl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryActiveAt(mainTest *testing.T) {
+	start := time.Date(2022, 10, 1, 4, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 10, 8, 4, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		advisory Advisory
+		at       time.Time
+		expected bool
+	}{
+		{
+			name:     "unbounded",
+			advisory: Advisory{},
+			at:       start,
+			expected: true,
+		},
+		{
+			name:     "before the window",
+			advisory: Advisory{StartTime: start, EndTime: end},
+			at:       start.Add(-time.Second),
+		},
+		{
+			name:     "start is inclusive",
+			advisory: Advisory{StartTime: start, EndTime: end},
+			at:       start,
+			expected: true,
+		},
+		{
+			name:     "end is exclusive",
+			advisory: Advisory{StartTime: start, EndTime: end},
+			at:       end,
+		},
+		{
+			name:     "open ended",
+			advisory: Advisory{StartTime: start},
+			at:       end.AddDate(1, 0, 0),
+			expected: true,
+		},
+		{
+			name:     "instants compare across zones",
+			advisory: Advisory{TimezoneName: "America/New_York", StartTime: start, EndTime: end},
+			at:       time.Date(2022, 10, 1, 0, 0, 0, 0, time.FixedZone("EDT", -4*60*60)),
+			expected: true,
+		},
+		{
+			name:     "empty window",
+			advisory: Advisory{StartTime: start, EndTime: start},
+			at:       start,
+		},
+		{
+			name:     "end before start",
+			advisory: Advisory{StartTime: end, EndTime: start},
+			at:       start,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		t.Equal(tc.expected, tc.advisory.ActiveAt(tc.at), tc.name)
+	}
+}
+
+func TestAdvisoryLocalWindow(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	a := Advisory{
+		TimezoneName: "America/Denver",
+		StartTime:    time.Date(2022, 7, 4, 18, 0, 0, 0, time.UTC),
+	}
+
+	start, end, err := a.LocalWindow()
+	if err != nil {
+		mainTest.Skip("no tzdata available:", err)
+	}
+
+	t.Equal("2022-07-04T12:00:00-06:00", start.Format(time.RFC3339))
+	t.True(start.Equal(a.StartTime))
+	t.True(end.IsZero())
+
+	_, _, err = Advisory{TimezoneName: "Nowhere/Special"}.LocalWindow()
+	t.Error(err)
+
+	start, _, err = Advisory{StartTime: a.StartTime}.LocalWindow()
+	if t.Nil(err) {
+		t.Equal(time.UTC, start.Location())
+	}
+}
@@ -1,8 +1,11 @@
 package asl
 
-/*
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -26,10 +29,16 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	examplePoint, err = geom.NewPoint(geom.Coordinates{XY: geom.XY{X: 1, Y: 2}})
+	if err != nil {
+		panic(err)
+	}
 }
 
 var (
 	exampleGeom1 geom.Polygon
+	examplePoint geom.Point
 )
 
 func TestMarshalJSON(mainTest *testing.T) {
@@ -42,14 +51,14 @@ func TestMarshalJSON(mainTest *testing.T) {
 	}{
 		{
 			name:     "base case (geometry should never be null; just know GeometryCollection isn't acceptable)",
-			expected: []byte(`{"type":"Feature","geometry":{"type":"GeometryCollection","geometries":[]},"properties":{"advisoryCategory":"AdvisoryCategoryType(0)","altitudeLower":0,"altitudeUpper":0,"contactEmail":null,"contactPhone":null,"countryGeoID":"","createdBy":"","endTime":"0001-01-01T00:00:00Z","geoID":"","id":"","lastEditedBy":"","name":"","ovn":"","published":false,"referenceNumber":null,"startTime":"0001-01-01T00:00:00Z","tags":null,"timezoneName":"","url":null,"version":0}}`),
+			expected: []byte(`{"type":"Feature","geometry":{"type":"GeometryCollection","geometries":[]},"properties":{"advisoryCategory":"","altitudeLower":0,"altitudeUpper":0,"contactEmail":null,"contactPhone":null,"countryGeoID":"","createdBy":"","endTime":"0001-01-01T00:00:00Z","geoID":"","id":"","lastEditedBy":"","name":"","ovn":"","published":false,"referenceNumber":null,"startTime":"0001-01-01T00:00:00Z","tags":null,"timezoneName":"","url":null,"version":0}}`),
 		},
 		{
 			name: "just geom",
 			arg: Advisory{
 				Geometry: exampleGeom1.AsGeometry(),
 			},
-			expected: []byte(`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-85.38391174432391,38.782187748582714],[34.782107793927395,32.085243181703234],[-77.03652118394466,38.897601427166194],[-85.38391174432391,38.782187748582714]]]},"properties":{"advisoryCategory":"AdvisoryCategoryType(0)","altitudeLower":0,"altitudeUpper":0,"contactEmail":null,"contactPhone":null,"countryGeoID":"","createdBy":"","endTime":"0001-01-01T00:00:00Z","geoID":"","id":"","lastEditedBy":"","name":"","ovn":"","published":false,"referenceNumber":null,"startTime":"0001-01-01T00:00:00Z","tags":null,"timezoneName":"","url":null,"version":0}}`),
+			expected: []byte(`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-85.38391174432391,38.782187748582714],[34.782107793927395,32.085243181703234],[-77.03652118394466,38.897601427166194],[-85.38391174432391,38.782187748582714]]]},"properties":{"advisoryCategory":"","altitudeLower":0,"altitudeUpper":0,"contactEmail":null,"contactPhone":null,"countryGeoID":"","createdBy":"","endTime":"0001-01-01T00:00:00Z","geoID":"","id":"","lastEditedBy":"","name":"","ovn":"","published":false,"referenceNumber":null,"startTime":"0001-01-01T00:00:00Z","tags":null,"timezoneName":"","url":null,"version":0}}`),
 		},
 		{
 			name: "only fields",
@@ -58,9 +67,9 @@ func TestMarshalJSON(mainTest *testing.T) {
 				GeoID:            "uqhroh3o",
 				AdvisoryCategory: Admin,
 				Name:             "oj2oiejqwo",
-				Tags:             []string{"asdh8", "a9ud9"},
-				AltitudeLower:    100,
-				AltitudeUpper:    200,
+				Tags:             []AdvisoryTag{"asdh8", "a9ud9"},
+				AltitudeLower:    FeetAGL(100),
+				AltitudeUpper:    FeetAGL(200),
 				StartTime:        time.Date(1902, 10, 2, 3, 5, 6, 11, time.UTC),
 				EndTime:          time.Date(2011, 11, 8, 1, 7, 3, 22, time.UTC),
 				TimezoneName:     "ajisodjaosd",
@@ -85,9 +94,9 @@ func TestMarshalJSON(mainTest *testing.T) {
 				Geometry:         exampleGeom1.AsGeometry(),
 				AdvisoryCategory: Admin,
 				Name:             "oj2oiejqwo",
-				Tags:             []string{"asdh8", "a9ud9"},
-				AltitudeLower:    100,
-				AltitudeUpper:    200,
+				Tags:             []AdvisoryTag{"asdh8", "a9ud9"},
+				AltitudeLower:    FeetAGL(100),
+				AltitudeUpper:    FeetAGL(200),
 				StartTime:        time.Date(1902, 10, 2, 3, 5, 6, 11, time.UTC),
 				EndTime:          time.Date(2011, 11, 8, 1, 7, 3, 22, time.UTC),
 				TimezoneName:     "ajisodjaosd",
@@ -102,18 +111,190 @@ func TestMarshalJSON(mainTest *testing.T) {
 				OVN:              "128h3910jidoqwnoq",
 				Version:          2,
 			},
-			expected: []byte(`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-85.38391174432391,38.782187748582714],[34.782107793927395,32.085243181703234],[-77.03652118394466,38.897601427166194],[-85.38391174432391,38.782187748582714]]]},"properties":{"advisoryCategory":"admin","altitudeLower":100,"altitudeUpper":200,"contactEmail":"sh08dajsid","contactPhone":"asjfasf","countryGeoID":"kasojdiad","createdBy":"asjidh8ajd0ip","endTime":"2011-11-08T01:07:03.000000022Z","geoID":"uqhroh3o","id":"heo2","lastEditedBy":"h89123h1","name":"oj2oiejqwo","ovn":"128h3910jidoqwnoq","published":true,"referenceNumber":"sh08dajsid","startTime":"1902-10-02T03:05:06.000000011Z","tags":["asdh8","a9ud9"],"timezoneName":"ajisodjaosd","url":{"Scheme":"https","Opaque":"","User":null,"Host":"airspacelink.com:199","Path":"/asdasd","RawPath":"","OmitHost":false,"ForceQuery":false,"RawQuery":"","Fragment":"","RawFragment":""},"version":2}}`),
+			expected: []byte(`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-85.38391174432391,38.782187748582714],[34.782107793927395,32.085243181703234],[-77.03652118394466,38.897601427166194],[-85.38391174432391,38.782187748582714]]]},"properties":{"advisoryCategory":"admin","altitudeLower":100,"altitudeUpper":200,"contactEmail":"sh08dajsid","contactPhone":"asjfasf","countryGeoID":"kasojdiad","createdBy":"asjidh8ajd0ip","endTime":"2011-11-08T01:07:03.000000022Z","geoID":"uqhroh3o","id":"heo2","lastEditedBy":"h89123h1","name":"oj2oiejqwo","ovn":"128h3910jidoqwnoq","published":true,"referenceNumber":"sh08dajsid","startTime":"1902-10-02T03:05:06.000000011Z","tags":["asdh8","a9ud9"],"timezoneName":"ajisodjaosd","url":"asjfasf","version":2}}`),
 		},
 	}
 
 	t := assert.New(mainTest)
 	for _, tc := range testCases {
 		actual, actualErr := tc.arg.MarshalJSON()
-		fmt.Println(string(actual))
 		if t.Nil(actualErr, tc.name+" should never return an error") {
 			t.Equal(tc.expected, actual, tc.name)
 		}
 	}
 }
 
-*/
+func TestUnmarshalJSON(mainTest *testing.T) {
+	randStr1 := "sh08dajsid"
+
+	testCases := []struct {
+		name        string
+		arg         []byte
+		expected    Advisory
+		expectedErr string
+	}{
+		{
+			name: "geometry and properties",
+			arg:  []byte(`{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[-85.38391174432391,38.782187748582714],[34.782107793927395,32.085243181703234],[-77.03652118394466,38.897601427166194],[-85.38391174432391,38.782187748582714]]]},"properties":{"advisoryCategory":"admin","altitudeLower":100,"contactEmail":"sh08dajsid","contactPhone":null,"id":"heo2","tags":["asdh8"],"version":2}}`),
+			expected: Advisory{
+				ID:               "heo2",
+				Geometry:         exampleGeom1.AsGeometry(),
+				AdvisoryCategory: Admin,
+				Tags:             []AdvisoryTag{"asdh8"},
+				AltitudeLower:    FeetAGL(100),
+				ContactEmail:     &randStr1,
+				Version:          2,
+			},
+		},
+		{
+			name:     "missing properties",
+			arg:      []byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]}}`),
+			expected: Advisory{Geometry: examplePoint.AsGeometry()},
+		},
+		{
+			name:     "unknown category",
+			arg:      []byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"advisoryCategory":"Seasonal"}}`),
+			expected: Advisory{Geometry: examplePoint.AsGeometry(), AdvisoryCategory: "seasonal"},
+		},
+		{
+			name:        "malformed properties",
+			arg:         []byte(`{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"version":"two"}}`),
+			expectedErr: "cannot unmarshal string",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		var actual Advisory
+		actualErr := actual.UnmarshalJSON(tc.arg)
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		if !t.Nil(actualErr, tc.name) {
+			continue
+		}
+
+		// geometries hold pointers, so compare them separately
+		t.True(geom.ExactEquals(tc.expected.Geometry, actual.Geometry), tc.name)
+		tc.expected.Geometry, actual.Geometry = geom.Geometry{}, geom.Geometry{}
+		t.Equal(tc.expected, actual, tc.name)
+	}
+}
+
+func TestQueryAdvisoriesByGeom(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	var actualBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Equal(http.MethodPost, r.Method)
+		t.Equal("/v4/advisories", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&actualBody)
+
+		w.Write([]byte(`{"statusCode":200,"message":"success","data":[{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"id":"a1","advisoryCategory":"emergency"}}]}`))
+	}))
+	defer srv.Close()
+
+	client := &Client{BaseURL: srv.URL}
+	resp, err := client.QueryAdvisoriesByGeom(context.Background(), &QueryAdvisoriesArgs{
+		Geom:          exampleGeom1.AsGeometry(),
+		AltitudeUpper: FeetAGL(400),
+		GeoIDs:        []string{"US"},
+	})
+
+	if t.Nil(err) && t.Len(resp.Data, 1) {
+		t.Equal("a1", resp.Data[0].ID)
+		t.Equal(Emergency, resp.Data[0].AdvisoryCategory)
+		t.True(resp.Data[0].Geometry.IsPoint())
+	}
+
+	t.Equal("Polygon", actualBody["geometry"].(map[string]any)["type"])
+	t.Equal(400.0, actualBody["altitudeUpper"])
+	t.Equal([]any{"US"}, actualBody["geoIDs"])
+}
+
+func TestQueryAdvisoriesByBBox(mainTest *testing.T) {
+	testCases := []struct {
+		name          string
+		env           geom.Envelope
+		filters       *AdvisoryFilters
+		expectedQuery url.Values
+		expectedErr   string
+	}{
+		{
+			name:        "empty envelope",
+			expectedErr: "bounding box is empty",
+		},
+		{
+			name: "no filters",
+			env:  exampleGeom1.Envelope(),
+			expectedQuery: url.Values{
+				"bbox": {"-85.38391174432391,32.085243181703234,34.782107793927395,38.897601427166194"},
+			},
+		},
+		{
+			name: "every filter",
+			env:  exampleGeom1.Envelope(),
+			filters: &AdvisoryFilters{
+				AltitudeLower: &Altitude{Value: 0.5, Unit: Feet, Reference: AGL},
+				AltitudeUpper: &Altitude{Value: 400, Unit: Feet, Reference: AGL},
+				StartTime:     time.Date(2022, 10, 2, 3, 5, 6, 0, time.FixedZone("EDT", -4*60*60)),
+				EndTime:       time.Date(2022, 10, 3, 3, 5, 6, 0, time.UTC),
+				GeoIDs:        []string{"US", "US-KY"},
+			},
+			expectedQuery: url.Values{
+				"bbox":          {"-85.38391174432391,32.085243181703234,34.782107793927395,38.897601427166194"},
+				"altitudeLower": {"0.5"},
+				"altitudeUpper": {"400"},
+				"startTime":     {"2022-10-02T07:05:06Z"},
+				"endTime":       {"2022-10-03T03:05:06Z"},
+				"geoIDs":        {"US,US-KY"},
+			},
+		},
+		{
+			name: "zero altitude and sub-second times",
+			env:  exampleGeom1.Envelope(),
+			filters: &AdvisoryFilters{
+				AltitudeLower: &Altitude{},
+				StartTime:     time.Date(2022, 10, 2, 3, 5, 6, 250_000_000, time.UTC),
+			},
+			expectedQuery: url.Values{
+				"bbox":          {"-85.38391174432391,32.085243181703234,34.782107793927395,38.897601427166194"},
+				"altitudeLower": {"0"},
+				"startTime":     {"2022-10-02T03:05:06.25Z"},
+			},
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		var actualQuery url.Values
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Equal(http.MethodGet, r.Method, tc.name)
+			t.Equal("/v4/advisories", r.URL.Path, tc.name)
+			actualQuery = r.URL.Query()
+
+			w.Write([]byte(`{"statusCode":200,"data":[{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"id":"a1"}}]}`))
+		}))
+
+		client := &Client{BaseURL: srv.URL}
+		resp, actualErr := client.QueryAdvisoriesByBBox(context.Background(), tc.env, tc.filters)
+		srv.Close()
+
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		if t.Nil(actualErr, tc.name) && t.Len(resp.Data, 1, tc.name) {
+			t.Equal("a1", resp.Data[0].ID, tc.name)
+		}
+
+		t.Equal(tc.expectedQuery, actualQuery, tc.name)
+	}
+}
@@ -0,0 +1,188 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/peterstace/simplefeatures/geom"
+)
+
+// AdvisoryEventType says what happened to an advisory between two polls
+type AdvisoryEventType string
+
+const (
+	AdvisoryAdded    AdvisoryEventType = "added"
+	AdvisoryModified AdvisoryEventType = "modified"
+	AdvisoryRemoved  AdvisoryEventType = "removed"
+
+	// AdvisoryWatchFailed events carry a failed poll's error in Err. The
+	// watch keeps going, backing off until a poll succeeds again. They
+	// also report advisories a poll dropped for having no ID
+	AdvisoryWatchFailed AdvisoryEventType = "failed"
+)
+
+// ErrAdvisoryWithoutID is carried by AdvisoryWatchFailed events when a
+// poll returned advisories without an ID, which can't be told apart
+// between polls and so are left out of the watch
+var ErrAdvisoryWithoutID = errors.New("advisory has no ID")
+
+// DefaultWatchInterval is how often Watch polls when given no interval
+const DefaultWatchInterval = time.Minute
+
+// maxWatchBackoff caps how far a watch backs off after failed polls,
+// unless its interval is longer
+const maxWatchBackoff = 5 * time.Minute
+
+// AdvisoryEvent is a change seen by Watch. Removed events carry the
+// advisory as it was last seen; modified events also carry it in Previous
+type AdvisoryEvent struct {
+	Type     AdvisoryEventType
+	Advisory Advisory
+	Previous *Advisory
+	Err      error
+}
+
+// Watch polls the advisories intersecting area every interval and sends
+// what changed between polls on the returned channel. The first poll
+// reports everything in the area as added. Advisories are matched by ID
+// and count as modified when their OVN or Version changes. Failed polls
+// are reported as AdvisoryWatchFailed events and retried with an
+// exponential backoff. An interval of zero or less uses
+// DefaultWatchInterval. The channel is closed once ctx is done
+func (c *Client) Watch(ctx context.Context, area geom.Geometry, interval time.Duration) <-chan AdvisoryEvent {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	events := make(chan AdvisoryEvent)
+
+	go func() {
+		defer close(events)
+
+		var seen map[string]Advisory
+		failures := 0
+		for {
+			current, dropped, err := c.pollAdvisories(ctx, area)
+			if ctx.Err() != nil {
+				return
+			}
+
+			wait := interval
+			if err != nil {
+				failures++
+				wait = watchBackoff(interval, failures)
+				if !sendEvent(ctx, events, AdvisoryEvent{Type: AdvisoryWatchFailed, Err: err}) {
+					return
+				}
+			} else {
+				failures = 0
+				if dropped > 0 {
+					err := fmt.Errorf("dropped %d advisories: %w", dropped, ErrAdvisoryWithoutID)
+					if !sendEvent(ctx, events, AdvisoryEvent{Type: AdvisoryWatchFailed, Err: err}) {
+						return
+					}
+				}
+
+				for _, ev := range diffAdvisories(seen, current) {
+					if !sendEvent(ctx, events, ev) {
+						return
+					}
+				}
+
+				seen = current
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// pollAdvisories fetches every advisory in area, keyed by ID,
+// counting the ones without an ID it had to drop
+func (c *Client) pollAdvisories(ctx context.Context, area geom.Geometry) (map[string]Advisory, int, error) {
+	it := c.IterateAdvisories(ctx, &QueryAdvisoriesArgs{Geom: area}, 0)
+	defer it.Close()
+
+	current := map[string]Advisory{}
+	dropped := 0
+	for it.Next() {
+		a := it.Advisory()
+		if a.ID == "" {
+			dropped++
+			continue
+		}
+
+		current[a.ID] = a
+	}
+
+	return current, dropped, it.Err()
+}
+
+// diffAdvisories lists the changes from prev to next: additions and
+// modifications first, then removals, each sorted by ID
+func diffAdvisories(prev, next map[string]Advisory) []AdvisoryEvent {
+	var changed, removed []AdvisoryEvent
+	for id, a := range next {
+		old, ok := prev[id]
+		switch {
+		case !ok:
+			changed = append(changed, AdvisoryEvent{Type: AdvisoryAdded, Advisory: a})
+		case old.OVN != a.OVN || old.Version != a.Version:
+			old := old
+			changed = append(changed, AdvisoryEvent{Type: AdvisoryModified, Advisory: a, Previous: &old})
+		}
+	}
+
+	for id, a := range prev {
+		if _, ok := next[id]; !ok {
+			removed = append(removed, AdvisoryEvent{Type: AdvisoryRemoved, Advisory: a})
+		}
+	}
+
+	for _, evs := range [][]AdvisoryEvent{changed, removed} {
+		sort.Slice(evs, func(i, j int) bool { return evs[i].Advisory.ID < evs[j].Advisory.ID })
+	}
+
+	return append(changed, removed...)
+}
+
+// watchBackoff doubles interval for every consecutive failure,
+// up to maxWatchBackoff
+func watchBackoff(interval time.Duration, failures int) time.Duration {
+	ceiling := maxWatchBackoff
+	if interval > ceiling {
+		ceiling = interval
+	}
+
+	wait := interval
+	for i := 0; i < failures && wait < ceiling; i++ {
+		wait *= 2
+	}
+
+	if wait > ceiling {
+		return ceiling
+	}
+
+	return wait
+}
+
+// sendEvent delivers ev unless ctx is done first
+func sendEvent(ctx context.Context, events chan<- AdvisoryEvent, ev AdvisoryEvent) bool {
+	select {
+	case events <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,162 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	// each poll serves the next snapshot, repeating the last one
+	snapshots := []string{
+		`[{"properties":{"id":"a","ovn":"1","version":1}},{"properties":{"id":"b","ovn":"1","version":1}},{"properties":{"name":"x"}},{"properties":{"name":"y"}}]`,
+		"",
+		`[{"properties":{"id":"a","ovn":"2","version":2}},{"properties":{"id":"c","ovn":"1","version":1}}]`,
+	}
+
+	var mu sync.Mutex
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		snapshot := snapshots[polls]
+		if polls < len(snapshots)-1 {
+			polls++
+		}
+		mu.Unlock()
+
+		if snapshot == "" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		fmt.Fprintf(w, `{"statusCode":200,"data":%s}`, snapshot)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events := (&Client{BaseURL: srv.URL}).Watch(ctx, exampleGeom1.AsGeometry(), time.Millisecond)
+
+	var actual []string
+	for len(actual) < 7 {
+		ev, ok := <-events
+		if !t.True(ok, "events closed early") {
+			return
+		}
+
+		desc := fmt.Sprintf("%s %s v%d", ev.Type, ev.Advisory.ID, ev.Advisory.Version)
+		if ev.Previous != nil {
+			desc += fmt.Sprintf(" from v%d", ev.Previous.Version)
+		}
+
+		switch {
+		case errors.Is(ev.Err, ErrAdvisoryWithoutID):
+			desc = fmt.Sprintf("%s %s", ev.Type, ev.Err)
+		case ev.Err != nil:
+			desc = fmt.Sprintf("%s %v", ev.Type, strings.Contains(ev.Err.Error(), "502"))
+		}
+
+		actual = append(actual, desc)
+	}
+
+	t.Equal([]string{
+		"failed dropped 2 advisories: advisory has no ID",
+		"added a v1",
+		"added b v1",
+		"failed true",
+		"modified a v2 from v1",
+		"added c v1",
+		"removed b v1",
+	}, actual)
+
+	// nothing changes after the last snapshot, and cancelling closes the channel
+	select {
+	case ev := <-events:
+		t.Fail("unexpected event", ev)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchDefaultInterval(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	var mu sync.Mutex
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		mu.Unlock()
+
+		w.Write([]byte(`{"statusCode":200,"data":[{"properties":{"id":"a"}}]}`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := (&Client{BaseURL: srv.URL}).Watch(ctx, exampleGeom1.AsGeometry(), 0)
+
+	ev := <-events
+	t.Equal(AdvisoryAdded, ev.Type)
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	for range events {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	t.Equal(1, polls)
+}
+
+func TestWatchBackoff(mainTest *testing.T) {
+	testCases := []struct {
+		name     string
+		interval time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{
+			name:     "first failure",
+			interval: time.Second,
+			failures: 1,
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "third failure",
+			interval: time.Second,
+			failures: 3,
+			expected: 8 * time.Second,
+		},
+		{
+			name:     "capped",
+			interval: time.Second,
+			failures: 40,
+			expected: maxWatchBackoff,
+		},
+		{
+			name:     "interval longer than the cap",
+			interval: time.Hour,
+			failures: 2,
+			expected: time.Hour,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		t.Equal(tc.expected, watchBackoff(tc.interval, tc.failures), tc.name)
+	}
+}
@@ -0,0 +1,213 @@
+package asl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrAltitudeReference is returned when altitudes with different reference
+// datums are compared or converted. Going between AGL, MSL and the WGS84
+// ellipsoid needs terrain and geoid models this package doesn't have
+var ErrAltitudeReference = errors.New("altitudes have different reference datums")
+
+// AltitudeUnit is the unit an altitude is measured in
+type AltitudeUnit string
+
+const (
+	Feet   AltitudeUnit = "ft"
+	Meters AltitudeUnit = "m"
+)
+
+const metersPerFoot = 0.3048
+
+// AltitudeReference is the datum an altitude is measured from
+type AltitudeReference string
+
+const (
+	// AGL is above ground level
+	AGL AltitudeReference = "AGL"
+
+	// MSL is above mean sea level
+	MSL AltitudeReference = "MSL"
+
+	// WGS84 is above the WGS84 ellipsoid, as reported by GPS
+	WGS84 AltitudeReference = "WGS84"
+)
+
+// The API's numeric altitude fields are in feet above ground level.
+// Altitudes are converted to these when they're marshalled as numbers
+const (
+	APIAltitudeUnit      = Feet
+	APIAltitudeReference = AGL
+)
+
+// Altitude is a height along with its unit and reference datum, so
+// altitudes from different sources can be compared safely. Empty units
+// and references are read as the API's. The zero value reads as 0 ft
+// AGL, but also marks an altitude as unset, which matters when bands
+// are compared: 0 in an explicit datum is a real bound in that datum.
+// AGL altitudes marshal to JSON as the bare number the API expects,
+// others as an object keeping their unit and datum
+type Altitude struct {
+	Value     float64
+	Unit      AltitudeUnit
+	Reference AltitudeReference
+}
+
+// FeetAGL returns an altitude of v feet above ground level
+func FeetAGL(v float64) Altitude { return Altitude{Value: v, Unit: Feet, Reference: AGL} }
+
+// MetersAGL returns an altitude of v meters above ground level
+func MetersAGL(v float64) Altitude { return Altitude{Value: v, Unit: Meters, Reference: AGL} }
+
+// IsZero reports whether a is the zero Altitude, i.e. unset. An
+// altitude of 0 with a unit or reference isn't
+func (a Altitude) IsZero() bool { return a == Altitude{} }
+
+func (a Altitude) unit() AltitudeUnit {
+	if a.Unit == "" {
+		return APIAltitudeUnit
+	}
+
+	return a.Unit
+}
+
+func (a Altitude) reference() AltitudeReference {
+	if a.Reference == "" {
+		return APIAltitudeReference
+	}
+
+	return a.Reference
+}
+
+// In converts the altitude to unit, keeping its reference
+func (a Altitude) In(unit AltitudeUnit) Altitude {
+	v := a.Value
+	switch from := a.unit(); {
+	case from == Feet && unit == Meters:
+		v *= metersPerFoot
+	case from == Meters && unit == Feet:
+		v /= metersPerFoot
+	}
+
+	return Altitude{Value: v, Unit: unit, Reference: a.reference()}
+}
+
+// Feet returns the altitude's value in feet
+func (a Altitude) Feet() float64 { return a.In(Feet).Value }
+
+// Meters returns the altitude's value in meters
+func (a Altitude) Meters() float64 { return a.In(Meters).Value }
+
+// Compare returns -1, 0 or 1 as a is below, equal to or above b,
+// or ErrAltitudeReference if their datums differ
+func (a Altitude) Compare(b Altitude) (int, error) {
+	if a.reference() != b.reference() {
+		return 0, fmt.Errorf("comparing %s to %s: %w", a, b, ErrAltitudeReference)
+	}
+
+	switch fa, fb := a.Feet(), b.Feet(); {
+	case fa < fb:
+		return -1, nil
+	case fa > fb:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (a Altitude) String() string {
+	return fmt.Sprintf("%s %s %s", strconv.FormatFloat(a.Value, 'f', -1, 64), a.unit(), a.reference())
+}
+
+// apiValue converts the altitude to the number the API expects
+func (a Altitude) apiValue() (float64, error) {
+	if a.reference() != APIAltitudeReference {
+		return 0, fmt.Errorf("expressing %s as %s: %w", a, APIAltitudeReference, ErrAltitudeReference)
+	}
+
+	return a.In(APIAltitudeUnit).Value, nil
+}
+
+// MarshalJSON encodes the altitude as a bare number in the API's unit
+// and reference. The API has no way to take other references, so those
+// fail with ErrAltitudeReference rather than sending a wrong number
+func (a Altitude) MarshalJSON() ([]byte, error) {
+	v, err := a.apiValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON accepts either a bare number in the API's unit and
+// reference, or an object like {"value": 120, "units": "m", "reference": "MSL"}
+func (a *Altitude) UnmarshalJSON(buf []byte) error {
+	buf = bytes.TrimSpace(buf)
+	if bytes.Equal(buf, []byte("null")) {
+		return nil
+	}
+
+	if len(buf) == 0 || buf[0] != '{' {
+		var v float64
+		if err := json.Unmarshal(buf, &v); err != nil {
+			return err
+		}
+
+		*a = Altitude{Value: v, Unit: APIAltitudeUnit, Reference: APIAltitudeReference}
+		return nil
+	}
+
+	var obj struct {
+		Value     float64 `json:"value"`
+		Units     string  `json:"units"`
+		Unit      string  `json:"unit"`
+		Reference string  `json:"reference"`
+	}
+
+	if err := json.Unmarshal(buf, &obj); err != nil {
+		return err
+	}
+
+	unit, err := parseAltitudeUnit(firstNonEmpty(obj.Units, obj.Unit))
+	if err != nil {
+		return err
+	}
+
+	ref, err := parseAltitudeReference(obj.Reference)
+	if err != nil {
+		return err
+	}
+
+	*a = Altitude{Value: obj.Value, Unit: unit, Reference: ref}
+	return nil
+}
+
+func parseAltitudeUnit(s string) (AltitudeUnit, error) {
+	switch strings.ToLower(s) {
+	case "", "ft", "feet", "foot":
+		return Feet, nil
+	case "m", "meters", "metres", "meter", "metre":
+		return Meters, nil
+	default:
+		return "", fmt.Errorf("unknown altitude unit %q", s)
+	}
+}
+
+func parseAltitudeReference(s string) (AltitudeReference, error) {
+	switch strings.ToUpper(s) {
+	case "", "AGL", "SFC":
+		return AGL, nil
+	case "MSL", "AMSL":
+		return MSL, nil
+	case "WGS84", "W84", "HAE":
+		return WGS84, nil
+	default:
+		return "", fmt.Errorf("unknown altitude reference %q", s)
+	}
+}
@@ -0,0 +1,126 @@
+package asl
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAltitudeConversion(mainTest *testing.T) {
+	testCases := []struct {
+		name           string
+		arg            Altitude
+		expectedFeet   float64
+		expectedMeters float64
+	}{
+		{name: "zero value", expectedFeet: 0, expectedMeters: 0},
+		{name: "feet", arg: FeetAGL(1000), expectedFeet: 1000, expectedMeters: 304.8},
+		{name: "meters", arg: MetersAGL(3.048), expectedFeet: 10, expectedMeters: 3.048},
+		{name: "unit left empty is feet", arg: Altitude{Value: 100}, expectedFeet: 100, expectedMeters: 30.48},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		t.InDelta(tc.expectedFeet, tc.arg.Feet(), 1e-9, tc.name)
+		t.InDelta(tc.expectedMeters, tc.arg.Meters(), 1e-9, tc.name)
+	}
+}
+
+func TestAltitudeCompare(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		a, b        Altitude
+		expected    int
+		expectedErr error
+	}{
+		{name: "equal across units", a: FeetAGL(100), b: MetersAGL(30.48), expected: 0},
+		{name: "below", a: MetersAGL(30), b: FeetAGL(100), expected: -1},
+		{name: "above", a: MetersAGL(31), b: FeetAGL(100), expected: 1},
+		{name: "empty reference is AGL", a: Altitude{Value: 1}, b: FeetAGL(0), expected: 1},
+		{
+			name:        "different datums",
+			a:           FeetAGL(100),
+			b:           Altitude{Value: 100, Unit: Feet, Reference: MSL},
+			expectedErr: ErrAltitudeReference,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, actualErr := tc.a.Compare(tc.b)
+		if tc.expectedErr != nil {
+			t.True(errors.Is(actualErr, tc.expectedErr), tc.name)
+			continue
+		}
+
+		if t.Nil(actualErr, tc.name) {
+			t.Equal(tc.expected, actual, tc.name)
+		}
+	}
+}
+
+func TestAltitudeJSON(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		arg         string
+		expected    Altitude
+		expectedOut string
+		expectedErr string
+	}{
+		{
+			name:        "bare number",
+			arg:         `400`,
+			expected:    FeetAGL(400),
+			expectedOut: `400`,
+		},
+		{
+			name:        "object in meters",
+			arg:         `{"value": 30.48, "units": "M", "reference": "agl"}`,
+			expected:    MetersAGL(30.48),
+			expectedOut: `100`,
+		},
+		{
+			name:        "object in another datum can't be sent",
+			arg:         `{"value": 1200, "unit": "ft", "reference": "MSL"}`,
+			expected:    Altitude{Value: 1200, Unit: Feet, Reference: MSL},
+			expectedErr: ErrAltitudeReference.Error(),
+		},
+		{
+			name:        "object in meters and another datum can't be sent",
+			arg:         `{"value": 90, "units": "m", "reference": "WGS84"}`,
+			expected:    Altitude{Value: 90, Unit: Meters, Reference: WGS84},
+			expectedErr: ErrAltitudeReference.Error(),
+		},
+		{
+			name:        "unknown unit",
+			arg:         `{"value": 1, "units": "furlongs"}`,
+			expectedErr: `unknown altitude unit "furlongs"`,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		var actual Altitude
+		actualErr := json.Unmarshal([]byte(tc.arg), &actual)
+		if actualErr == nil {
+			t.Equal(tc.expected, actual, tc.name)
+
+			var out []byte
+			out, actualErr = json.Marshal(actual)
+			if actualErr == nil {
+				t.JSONEq(tc.expectedOut, string(out), tc.name)
+			}
+		}
+
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		t.Nil(actualErr, tc.name)
+	}
+}
@@ -1,14 +1,207 @@
 package asl
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Sentinel errors an *Err unwraps to based on its status,
+// so callers can branch with errors.Is
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+
+	// ErrConflict means a precondition like an advisory's OVN no
+	// longer matches what the server has
+	ErrConflict = errors.New("conflict")
+)
+
+// requestIDHeaders are checked in order for an ID to quote to support
+var requestIDHeaders = []string{
+	"X-Request-Id",
+	"X-Correlation-Id",
+	"Request-Id",
+	"Apim-Request-Id",
+}
+
+// FieldError is a validation problem with one field of a request
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"message"`
+}
+
 // Err wraps any error received back from the API
 type Err struct {
 	Status int
 	Msg    string
+
+	// Method and Endpoint identify the request that failed
+	Method   string
+	Endpoint string
+
+	// RequestID is the gateway's request or correlation ID, if it sent one
+	RequestID string
+
+	// Details lists field level validation problems, if the API sent any
+	Details []FieldError
+
+	// Header and Body are the raw response
+	Header http.Header
+	Body   []byte
+}
+
+// newErr captures a failed response into an *Err
+func newErr(req *http.Request, resp *http.Response, body []byte, msg string) *Err {
+	e := &Err{
+		Status:  resp.StatusCode,
+		Msg:     msg,
+		Method:  req.Method,
+		Header:  resp.Header,
+		Body:    body,
+		Details: parseFieldErrors(body),
+	}
+
+	if req.URL != nil {
+		e.Endpoint = req.URL.Path
+	}
+
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+
+	return e
 }
 
-func (e *Err) Error() string { return e.Msg }
-func (e *Err) Unwrap() error { return nil }
+// parseFieldErrors looks for validation details under "errors" or
+// "details", either as a list of objects or as a map of field to messages
+func parseFieldErrors(body []byte) []FieldError {
+	var envelope map[string]json.RawMessage
+	if json.Unmarshal(body, &envelope) != nil {
+		return nil
+	}
+
+	for _, key := range []string{"errors", "details"} {
+		raw, ok := envelope[key]
+		if !ok {
+			continue
+		}
+
+		var list []struct {
+			Field    string `json:"field"`
+			Path     string `json:"path"`
+			Property string `json:"property"`
+			Message  string `json:"message"`
+			Msg      string `json:"msg"`
+		}
+
+		if json.Unmarshal(raw, &list) == nil {
+			details := make([]FieldError, 0, len(list))
+			for _, v := range list {
+				details = append(details, FieldError{
+					Field: firstNonEmpty(v.Field, v.Path, v.Property),
+					Msg:   firstNonEmpty(v.Message, v.Msg),
+				})
+			}
+
+			return details
+		}
+
+		var byField map[string]json.RawMessage
+		if json.Unmarshal(raw, &byField) != nil {
+			continue
+		}
+
+		details := make([]FieldError, 0, len(byField))
+		for field, v := range byField {
+			var msgs []string
+			if json.Unmarshal(v, &msgs) != nil {
+				var msg string
+				json.Unmarshal(v, &msg)
+				msgs = []string{msg}
+			}
+
+			for _, msg := range msgs {
+				details = append(details, FieldError{Field: field, Msg: msg})
+			}
+		}
+
+		sort.SliceStable(details, func(i, j int) bool { return details[i].Field < details[j].Field })
+		return details
+	}
+
+	return nil
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (e *Err) Error() string {
+	msg := e.Msg
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+
+	var b strings.Builder
+	if e.Endpoint != "" {
+		fmt.Fprintf(&b, "%s %s: ", e.Method, e.Endpoint)
+	}
+
+	fmt.Fprintf(&b, "%d %s", e.Status, msg)
+	for _, d := range e.Details {
+		fmt.Fprintf(&b, "; %s: %s", d.Field, d.Msg)
+	}
+
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request ID %s)", e.RequestID)
+	}
+
+	return b.String()
+}
+
+// Unwrap returns the sentinel error matching the status, if any
+func (e *Err) Unwrap() error {
+	switch {
+	case e.Status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.Status == http.StatusForbidden:
+		return ErrForbidden
+	case e.Status == http.StatusNotFound:
+		return ErrNotFound
+	case e.Status == http.StatusConflict || e.Status == http.StatusPreconditionFailed:
+		return ErrConflict
+	case e.Status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.Status >= 500:
+		return ErrServer
+	default:
+		return nil
+	}
+}
+
+// Is matches any *Err, or only those with the same Status
+// when the target sets one, e.g. errors.Is(err, &Err{Status: 404})
 func (e *Err) Is(err error) bool {
-	_, is := err.(*Err)
-	return is
+	target, is := err.(*Err)
+	if !is {
+		return false
+	}
+
+	return target.Status == 0 || target.Status == e.Status
 }
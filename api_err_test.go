@@ -0,0 +1,179 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErr(mainTest *testing.T) {
+	testCases := []struct {
+		name            string
+		status          int
+		header          map[string]string
+		body            string
+		expectedIs      error
+		expectedMsg     string
+		expectedID      string
+		expectedDetails []FieldError
+	}{
+		{
+			name:        "unauthorized",
+			status:      401,
+			body:        `{"message":"jwt expired"}`,
+			expectedIs:  ErrUnauthorized,
+			expectedMsg: "POST /v2/surface: 401 jwt expired",
+		},
+		{
+			name:        "forbidden",
+			status:      403,
+			body:        `{"message":"missing scope"}`,
+			expectedIs:  ErrForbidden,
+			expectedMsg: "POST /v2/surface: 403 missing scope",
+		},
+		{
+			name:        "json without a message falls back to the body",
+			status:      404,
+			body:        `{}`,
+			expectedIs:  ErrNotFound,
+			expectedMsg: "POST /v2/surface: 404 {}",
+		},
+		{
+			name:        "oauth error",
+			status:      401,
+			header:      map[string]string{"Content-Type": "application/json"},
+			body:        `{"error":"invalid_client","error_description":"bad secret"}`,
+			expectedIs:  ErrUnauthorized,
+			expectedMsg: `POST /v2/surface: 401 {"error":"invalid_client","error_description":"bad secret"}`,
+		},
+		{
+			name:        "rate limited with a request ID",
+			status:      429,
+			header:      map[string]string{"X-Correlation-Id": "abc-123"},
+			body:        `{"message":"slow down"}`,
+			expectedIs:  ErrRateLimited,
+			expectedMsg: "POST /v2/surface: 429 slow down (request ID abc-123)",
+			expectedID:  "abc-123",
+		},
+		{
+			name:        "server error",
+			status:      503,
+			body:        `{"message":"try later"}`,
+			expectedIs:  ErrServer,
+			expectedMsg: "POST /v2/surface: 503 try later",
+		},
+		{
+			name:       "validation details as a list",
+			status:     400,
+			body:       `{"message":"invalid request","errors":[{"field":"resolution","message":"must be at most 15"},{"path":"layers[0].code","msg":"required"}]}`,
+			expectedIs: &Err{},
+			expectedDetails: []FieldError{
+				{Field: "resolution", Msg: "must be at most 15"},
+				{Field: "layers[0].code", Msg: "required"},
+			},
+			expectedMsg: "POST /v2/surface: 400 invalid request; resolution: must be at most 15; layers[0].code: required",
+		},
+		{
+			name:       "validation details as a map",
+			status:     422,
+			body:       `{"message":"invalid request","details":{"resolution":"too high","geometry":["empty","not a polygon"]}}`,
+			expectedIs: &Err{},
+			expectedDetails: []FieldError{
+				{Field: "geometry", Msg: "empty"},
+				{Field: "geometry", Msg: "not a polygon"},
+				{Field: "resolution", Msg: "too high"},
+			},
+			expectedMsg: "POST /v2/surface: 422 invalid request; geometry: empty; geometry: not a polygon; resolution: too high",
+		},
+		{
+			name:        "html error page",
+			status:      502,
+			header:      map[string]string{"Content-Type": "text/html"},
+			body:        "<html>\n  <body>\n    <h1>502 Bad Gateway</h1>\n  </body>\n</html>",
+			expectedIs:  ErrServer,
+			expectedMsg: "POST /v2/surface: 502 <html> <body> <h1>502 Bad Gateway</h1> </body> </html>",
+		},
+		{
+			name:        "empty body",
+			status:      504,
+			expectedIs:  ErrServer,
+			expectedMsg: "POST /v2/surface: 504 Gateway Timeout",
+		},
+		{
+			name:        "json content type with a broken body",
+			status:      400,
+			header:      map[string]string{"Content-Type": "application/json"},
+			body:        `{"message": "trunc`,
+			expectedIs:  &Err{},
+			expectedMsg: `POST /v2/surface: 400 {"message": "trunc`,
+		},
+		{
+			name:        "long bodies are truncated",
+			status:      500,
+			header:      map[string]string{"Content-Type": "text/plain"},
+			body:        strings.Repeat("é", 200),
+			expectedIs:  ErrServer,
+			expectedMsg: "POST /v2/surface: 500 " + strings.Repeat("é", 128) + "...",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range tc.header {
+				w.Header().Set(k, v)
+			}
+
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+
+		client := &Client{BaseURL: srv.URL}
+		_, actualErr := client.Surface(context.Background(), exampleSurfaceReq())
+		srv.Close()
+
+		var apiErr *Err
+		if !t.True(errors.As(actualErr, &apiErr), tc.name) {
+			continue
+		}
+
+		t.True(errors.Is(actualErr, tc.expectedIs), tc.name)
+		t.Equal(tc.expectedMsg, actualErr.Error(), tc.name)
+		t.Equal(tc.status, apiErr.Status, tc.name)
+		t.Equal(tc.expectedID, apiErr.RequestID, tc.name)
+		t.Equal(tc.expectedDetails, apiErr.Details, tc.name)
+		t.Equal(tc.body, string(apiErr.Body), tc.name)
+
+		for _, sentinel := range []error{ErrUnauthorized, ErrForbidden, ErrNotFound, ErrRateLimited, ErrServer} {
+			if sentinel != tc.expectedIs {
+				t.False(errors.Is(actualErr, sentinel), tc.name)
+			}
+		}
+	}
+}
+
+func TestErrIs(mainTest *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		target   error
+		expected bool
+	}{
+		{name: "any status", err: &Err{Status: 500}, target: &Err{}, expected: true},
+		{name: "same status", err: &Err{Status: 404}, target: &Err{Status: 404}, expected: true},
+		{name: "different status", err: &Err{Status: 500}, target: &Err{Status: 404}, expected: false},
+		{name: "wrapped", err: fmt.Errorf("get: %w", &Err{Status: 404}), target: &Err{Status: 404}, expected: true},
+		{name: "not an Err", err: errors.New("boom"), target: &Err{}, expected: false},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		t.Equal(tc.expected, errors.Is(tc.err, tc.target), tc.name)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 type Client struct {
@@ -17,27 +19,95 @@ type Client struct {
 	ClientID        string
 	ClientSecret    string
 
+	// Scopes are requested whenever the client authenticates on its own,
+	// until Authenticate is called with other scopes. Set it before the
+	// client is shared; the client never modifies it
+	Scopes []string
+
+	// ExpirySkew is how long before a token expires that the client
+	// considers it stale and fetches a new one. Defaults to DefaultExpirySkew
+	ExpirySkew time.Duration
+
+	// TokenSource supplies tokens in place of the client credentials flow,
+	// e.g. a StaticTokenSource or a FileTokenSource written by a sidecar.
+	// When set, ClientID, ClientSecret and Scopes are ignored
+	TokenSource TokenSource
+
+	// TokenCache, when set, persists client credentials tokens to disk
+	// so separate runs and processes can share them until they expire.
+	// Authenticate reuses a cached token that's still valid
+	TokenCache *FileTokenCache
+
+	// Retry controls how failed requests are retried. Nil means
+	// every request is attempted exactly once
+	Retry *RetryPolicy
+
+	// RateLimiter, when set, throttles every request the client sends
+	// to stay within the subscription's quotas
+	RateLimiter *RateLimiter
+
 	HTTPClient http.Client
 
 	BaseURL string
+
+	// Token is the cached token. Once the client is shared between
+	// goroutines, read and replace it with CurrentToken and SetToken
 	Token
+
+	// auth is created on first use and kept behind a pointer
+	// so Client values can still be copied
+	auth *authState
 }
 
-type Token struct {
-	AccessToken string    `json:"accessToken"`
-	Expiration  time.Time `json:"expires"`
-	Scopes      string    `json:"scope"`
+// Authenticate will grab a fresh JWT, replacing the existing cached
+// token. It's safe to call from multiple goroutines: callers asking for
+// the same scopes at the same time share a single token request
+func (c *Client) Authenticate(ctx context.Context, scopes ...string) error {
+	_, err := c.refreshToken(ctx, scopes, "")
+	return err
 }
 
-func (t Token) Expired() bool {
-	return time.Now().After(t.Expiration)
+// CurrentToken returns the cached token, which may be
+// empty or expired
+func (c *Client) CurrentToken() Token {
+	a := c.authState()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return c.Token
 }
 
-// Authenticate will grab a fresh JWT, replacing
-// the existing cached token
-func (c *Client) Authenticate(ctx context.Context, scopes ...string) error {
+// SetToken replaces the cached token, e.g. with one
+// persisted from an earlier run
+func (c *Client) SetToken(t Token) {
+	a := c.authState()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c.Token = t
+}
+
+// fetchToken asks the client's token source for a token, defaulting to
+// the client credentials flow. A token equal to rejected is never
+// returned from the on-disk cache
+func (c *Client) fetchToken(ctx context.Context, scopes []string, rejected string) (Token, error) {
+	if c.TokenSource != nil {
+		return c.TokenSource.Token(ctx)
+	}
+
+	if c.TokenCache == nil || !c.canAuthenticate() {
+		return c.requestClientCredentials(ctx, scopes)
+	}
+
+	key := tokenCacheKey(c.BaseURL, c.ClientID, scopes)
+	return c.TokenCache.get(ctx, key, rejected, c.expirySkew(), func(ctx context.Context) (Token, error) {
+		return c.requestClientCredentials(ctx, scopes)
+	})
+}
+
+// requestClientCredentials performs the client credentials
+// flow against the oauth endpoint
+func (c *Client) requestClientCredentials(ctx context.Context, scopes []string) (Token, error) {
 	if c.ClientID == "" || c.ClientSecret == "" || c.SubscriptionKey == "" {
-		return fmt.Errorf("missing client ID, client secret, or subscription key")
+		return Token{}, fmt.Errorf("missing client ID, client secret, or subscription key")
 	}
 
 	clientCredentials := url.Values{
@@ -58,22 +128,30 @@ func (c *Client) Authenticate(ctx context.Context, scopes ...string) error {
 	)
 
 	if err != nil {
-		return err
+		return Token{}, err
 	}
 
 	req.Header = http.Header{"x-api-key": []string{c.SubscriptionKey}}
 
-	auth0Resp, err := apiReq[Token](&c.HTTPClient, req)
+	// a second token is as good as the first
+	markIdempotent(req)
+
+	auth0Resp, err := apiReq[Token](c, req)
 	if err != nil {
-		return err
+		return Token{}, err
 	}
 
 	if auth0Resp.Status != 200 {
-		return fmt.Errorf("error authenticating: %s", auth0Resp.Msg)
+		return Token{}, fmt.Errorf("error authenticating: %s", auth0Resp.Msg)
 	}
 
-	c.Token = auth0Resp.Data
-	return err
+	return auth0Resp.Data, nil
+}
+
+// canAuthenticate reports whether the client has a token source
+// or the credentials needed to fetch tokens on its own
+func (c *Client) canAuthenticate() bool {
+	return c.TokenSource != nil || (c.ClientID != "" && c.ClientSecret != "" && c.SubscriptionKey != "")
 }
 
 func (c *Client) makeJSONReq(ctx context.Context, method, path string, body any) (*http.Request, error) {
@@ -86,6 +164,11 @@ func (c *Client) makeJSONReq(ctx context.Context, method, path string, body any)
 }
 
 func (c *Client) makeReq(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	token, err := c.validToken(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
 	if err != nil {
 		return nil, err
@@ -94,8 +177,8 @@ func (c *Client) makeReq(ctx context.Context, method, path string, body io.Reade
 	headers := make(map[string][]string, 2)
 	headers["Content-Type"] = []string{"application/json"}
 
-	if t := c.Token.AccessToken; t != "" {
-		headers["Authorization"] = []string{"Bearer " + t}
+	if token != "" {
+		headers["Authorization"] = []string{"Bearer " + token}
 	}
 
 	if subkey := c.SubscriptionKey; subkey != "" {
@@ -106,27 +189,131 @@ func (c *Client) makeReq(ctx context.Context, method, path string, body io.Reade
 	return req, nil
 }
 
-// apiReq will perform an HTTP request and then unmarshal the
-// response into the target struct pointer
-func apiReq[X any](client *http.Client, req *http.Request) (*Resp[X], error) {
-	resp, err := client.Do(req)
+// authedReq performs a request built by makeReq and then unmarshals
+// the response into the target struct pointer
+func authedReq[X any](c *Client, req *http.Request) (*Resp[X], error) {
+	resp, err := c.authedDo(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResp[X](req, resp)
+}
+
+// apiReq will perform an HTTP request, retrying per the client's
+// retry policy, and then unmarshal the response into the target
+// struct pointer
+func apiReq[X any](c *Client, req *http.Request) (*Resp[X], error) {
+	resp, err := c.checkedDo(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeResp[X](req, resp)
+}
+
+// authedDo performs a request built by makeReq. If the API rejects the
+// token with a 401, the client re-authenticates and retries exactly once
+func (c *Client) authedDo(req *http.Request) (*http.Response, error) {
+	resp, err := c.checkedDo(req)
+
+	var apiErr *Err
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized || !c.canAuthenticate() {
+		return resp, err
+	}
+
+	rejected := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	token, authErr := c.validToken(req.Context(), rejected)
+	if authErr != nil {
+		return nil, authErr
+	}
+
+	if !rewindable(req) {
+		return nil, err
+	}
+
+	retry, err := rewind(req)
 	if err != nil {
 		return nil, err
 	}
 
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return c.checkedDo(retry)
+}
+
+// checkedDo sends req per the client's retry policy and turns error
+// statuses into an *Err. On success the caller must close the body
+func (c *Client) checkedDo(req *http.Request) (*http.Response, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
 	buf, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	var apiResp Resp[X]
-	if err := json.Unmarshal(buf, &apiResp); err != nil {
+	// error bodies aren't always our JSON envelope (HTML 502 pages,
+	// empty 504s, OAuth errors), so fall back to a snippet of them
+	var apiResp Resp[json.RawMessage]
+	if !isJSON(resp.Header, buf) || json.Unmarshal(buf, &apiResp) != nil || apiResp.Msg == "" {
+		apiResp.Msg = snippet(buf)
+	}
+
+	return nil, newErr(req, resp, buf, apiResp.Msg)
+}
+
+// decodeResp reads and closes a successful response
+func decodeResp[X any](req *http.Request, resp *http.Response) (*Resp[X], error) {
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
 		return nil, err
 	}
 
-	if code := resp.StatusCode; code >= 400 {
-		return nil, &Err{Status: code, Msg: apiResp.Msg}
+	var apiResp Resp[X]
+	if err := json.Unmarshal(buf, &apiResp); err != nil {
+		return nil, fmt.Errorf("decoding %s response from %s: %w: %q", resp.Header.Get("Content-Type"), req.URL.Path, err, snippet(buf))
 	}
 
 	return &apiResp, nil
 }
+
+// isJSON reports whether a response is JSON. Responses that don't say
+// so in their Content-Type are sniffed, except for HTML error pages
+func isJSON(h http.Header, body []byte) bool {
+	switch ct := h.Get("Content-Type"); {
+	case strings.Contains(ct, "json"):
+		return true
+	case strings.Contains(ct, "html"):
+		return false
+	default:
+		body = bytes.TrimSpace(body)
+		return len(body) > 0 && body[0] == '{'
+	}
+}
+
+// maxSnippet is how much of an unexpected body makes it into errors
+const maxSnippet = 256
+
+// snippet collapses the whitespace in body and truncates it to maxSnippet bytes
+func snippet(body []byte) string {
+	s := strings.Join(strings.Fields(string(body)), " ")
+	if len(s) <= maxSnippet {
+		return s
+	}
+
+	cut := maxSnippet
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + "..."
+}
@@ -2,8 +2,12 @@ package asl
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -128,3 +132,270 @@ func TestAuthenticate(mainTest *testing.T) {
 		}
 	}
 }
+
+// authServer mocks the oauth and surface endpoints. The surface endpoint
+// rejects expired tokens and any token other than the most recently issued one
+func authServer(expires time.Time, oauthHits *int) *httptest.Server {
+	var mu sync.Mutex
+	issued := ""
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/oauth/token":
+			// give concurrent callers time to pile up behind the refresh
+			time.Sleep(10 * time.Millisecond)
+			*oauthHits++
+			issued = fmt.Sprintf("token-%d", *oauthHits)
+			fmt.Fprintf(w, `{"statusCode":200,"data":{"accessToken":%q,"expires":%q}}`, issued, expires.Format(time.RFC3339Nano))
+		case "/v2/surface":
+			if r.Header.Get("Authorization") != "Bearer "+issued || expires.Before(time.Now()) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"message":"invalid token"}`))
+				return
+			}
+
+			w.Write([]byte(`{"statusCode":200,"data":[]}`))
+		}
+	}))
+}
+
+func TestTokenRefresh(mainTest *testing.T) {
+	testCases := []struct {
+		name              string
+		token             Token
+		expires           time.Time
+		expectedOAuthHits int
+	}{
+		{
+			name:              "no token yet",
+			expires:           time.Now().Add(time.Hour),
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "expired token",
+			token:             Token{AccessToken: "stale", Expiration: time.Now().Add(-time.Hour)},
+			expires:           time.Now().Add(time.Hour),
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "token inside the expiry skew",
+			token:             Token{AccessToken: "stale", Expiration: time.Now().Add(time.Second)},
+			expires:           time.Now().Add(time.Hour),
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "revoked token gets refreshed after a 401",
+			token:             Token{AccessToken: "revoked", Expiration: time.Now().Add(time.Hour)},
+			expires:           time.Now().Add(time.Hour),
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "401 is only retried once",
+			expires:           time.Now().Add(-time.Hour),
+			expectedOAuthHits: 2,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		oauthHits := 0
+		srv := authServer(tc.expires, &oauthHits)
+		client := Client{
+			SubscriptionKey: "something",
+			ClientID:        "something",
+			ClientSecret:    "something",
+			BaseURL:         srv.URL,
+			Token:           tc.token,
+		}
+
+		_, err := client.Surface(context.Background(), exampleSurfaceReq())
+		if tc.expires.Before(time.Now()) {
+			t.Error(err, tc.name)
+		} else {
+			t.Nil(err, tc.name)
+		}
+
+		t.Equal(tc.expectedOAuthHits, oauthHits, tc.name)
+		srv.Close()
+	}
+}
+
+func TestConcurrentTokenRefresh(mainTest *testing.T) {
+	testCases := []struct {
+		name              string
+		token             Token
+		callers           int
+		expectedOAuthHits int
+	}{
+		{
+			name:              "no token yet",
+			callers:           20,
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "every caller gets a 401 for the same revoked token",
+			token:             Token{AccessToken: "revoked", Expiration: time.Now().Add(time.Hour)},
+			callers:           20,
+			expectedOAuthHits: 1,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		oauthHits := 0
+		srv := authServer(time.Now().Add(time.Hour), &oauthHits)
+		client := &Client{
+			SubscriptionKey: "something",
+			ClientID:        "something",
+			ClientSecret:    "something",
+			BaseURL:         srv.URL,
+		}
+		client.SetToken(tc.token)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, tc.callers)
+		for i := 0; i < tc.callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := client.Surface(context.Background(), exampleSurfaceReq())
+				errs <- err
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Nil(err, tc.name)
+		}
+
+		t.Equal(tc.expectedOAuthHits, oauthHits, tc.name)
+		srv.Close()
+	}
+}
+
+func TestRefreshLeaderContext(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	// the first token request hangs until the test is over
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first := false
+		once.Do(func() { first = true })
+		if first {
+			close(started)
+			<-release
+			return
+		}
+
+		fmt.Fprintf(w, `{"statusCode":200,"data":{"accessToken":"fresh","expires":%q}}`, time.Now().Add(time.Hour).Format(time.RFC3339Nano))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := &Client{
+		SubscriptionKey: "something",
+		ClientID:        "something",
+		ClientSecret:    "something",
+		BaseURL:         srv.URL,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() { leaderErr <- client.Authenticate(ctx, "a") }()
+
+	<-started
+	waiterErr := make(chan error)
+	go func() { waiterErr <- client.Authenticate(context.Background(), "a") }()
+
+	// let the waiter queue up behind the leader before it gives up
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	t.True(errors.Is(<-leaderErr, context.Canceled))
+	t.NoError(<-waiterErr)
+	t.Equal("fresh", client.CurrentToken().AccessToken)
+	t.Nil(client.Scopes, "Authenticate shouldn't touch the configured scopes")
+}
+
+// closeTracker records whether response bodies get closed
+type closeTracker struct {
+	http.RoundTripper
+	closed int
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	tracker *closeTracker
+}
+
+func (b trackedBody) Close() error {
+	b.tracker.closed++
+	return b.ReadCloser.Close()
+}
+
+func (t *closeTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err == nil {
+		resp.Body = trackedBody{resp.Body, t}
+	}
+	return resp, err
+}
+
+func TestAPIReqBody(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		expectedErr string
+	}{
+		{
+			name:   "success",
+			status: 200,
+			body:   `{"statusCode":200,"data":[]}`,
+		},
+		{
+			name:        "error",
+			status:      400,
+			body:        `{"message":"bad"}`,
+			expectedErr: "POST /v2/surface: 400 bad",
+		},
+		{
+			name:        "success that isn't json",
+			contentType: "text/html",
+			status:      200,
+			body:        "<html>maintenance</html>",
+			expectedErr: `decoding text/html response from /v2/surface: invalid character '<' looking for beginning of value: "<html>maintenance</html>"`,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.contentType != "" {
+				w.Header().Set("Content-Type", tc.contentType)
+			}
+			w.WriteHeader(tc.status)
+			w.Write([]byte(tc.body))
+		}))
+
+		tracker := &closeTracker{RoundTripper: http.DefaultTransport}
+		client := &Client{BaseURL: srv.URL, HTTPClient: http.Client{Transport: tracker}}
+		_, actualErr := client.Surface(context.Background(), exampleSurfaceReq())
+		srv.Close()
+
+		t.Equal(1, tracker.closed, tc.name+" should close the response body")
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Equal(tc.expectedErr, actualErr.Error(), tc.name)
+			}
+			continue
+		}
+
+		t.Nil(actualErr, tc.name)
+	}
+}
@@ -0,0 +1,150 @@
+package asl
+
+import (
+	"sort"
+
+	"github.com/uber/h3-go/v3"
+)
+
+// HexCell is everything a surface response says about a single hex
+type HexCell struct {
+	// Props holds the props of each feature covering the hex, by layer alias
+	Props map[string][]map[string]any
+
+	// Score combines the Layer.Score of every layer covering the hex
+	Score float64
+}
+
+// Aliases lists the layers covering the cell, sorted
+func (c HexCell) Aliases() []string {
+	aliases := make([]string, 0, len(c.Props))
+	for alias := range c.Props {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+	return aliases
+}
+
+// ScoreReducer combines the scores of the layers covering a hex,
+// keyed by layer alias, into the hex's score
+type ScoreReducer func(scores map[string]float64) float64
+
+// SumScores adds up the layers' scores
+func SumScores(scores map[string]float64) float64 {
+	var sum float64
+	for _, s := range scores {
+		sum += s
+	}
+
+	return sum
+}
+
+// MaxScore takes the highest of the layers' scores
+func MaxScore(scores map[string]float64) float64 {
+	var max float64
+	first := true
+	for _, s := range scores {
+		if first || s > max {
+			max, first = s, false
+		}
+	}
+
+	return max
+}
+
+// WeightedAverageScore averages the layers' scores, weighting them by
+// alias. Layers without a weight count once
+func WeightedAverageScore(weights map[string]float64) ScoreReducer {
+	return func(scores map[string]float64) float64 {
+		var sum, total float64
+		for alias, s := range scores {
+			w, ok := weights[alias]
+			if !ok {
+				w = 1
+			}
+
+			sum += w * s
+			total += w
+		}
+
+		if total == 0 {
+			return 0
+		}
+
+		return sum / total
+	}
+}
+
+// LayerOf tells which layer a surface feature came from, by the
+// layer's alias, or its code when the layer has no alias
+type LayerOf func(f HexFeature) (alias string, ok bool)
+
+// LayerByProp reads a feature's layer from its string prop named key
+func LayerByProp(key string) LayerOf {
+	return func(f HexFeature) (string, bool) {
+		alias, ok := f.Props[key].(string)
+		return alias, ok && alias != ""
+	}
+}
+
+// AggregateHexes pivots a surface response into one cell per hex,
+// scoring each with reduce over the Score of the layers covering it.
+// layerOf matches features to layers; features it can't match, or that
+// match none of layers, are skipped. A nil layerOf defaults to
+// LayerByProp("alias"), and a nil reduce to SumScores
+func AggregateHexes(features []HexFeature, layers []Layer, layerOf LayerOf, reduce ScoreReducer) map[h3.H3Index]HexCell {
+	if layerOf == nil {
+		layerOf = LayerByProp("alias")
+	}
+
+	if reduce == nil {
+		reduce = SumScores
+	}
+
+	scores := make(map[string]float64, len(layers))
+	for _, l := range layers {
+		alias := l.Alias
+		if alias == "" {
+			alias = l.Code
+		}
+
+		scores[alias] = l.Score
+	}
+
+	cells := map[h3.H3Index]HexCell{}
+	for _, f := range features {
+		alias, ok := layerOf(f)
+		if !ok {
+			continue
+		} else if _, ok := scores[alias]; !ok {
+			continue
+		}
+
+		for hex, in := range f.Hexes {
+			if !in {
+				continue
+			}
+
+			cell, ok := cells[hex]
+			if !ok {
+				cell = HexCell{Props: map[string][]map[string]any{}}
+			}
+
+			cell.Props[alias] = append(cell.Props[alias], f.Props)
+			cells[hex] = cell
+		}
+	}
+
+	for hex, cell := range cells {
+		covering := make(map[string]float64, len(cell.Props))
+		for alias := range cell.Props {
+			covering[alias] = scores[alias]
+		}
+
+		cell.Score = reduce(covering)
+		cells[hex] = cell
+	}
+
+	return cells
+}
@@ -0,0 +1,110 @@
+package asl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/h3-go/v3"
+)
+
+func TestAggregateHexes(mainTest *testing.T) {
+	layers := []Layer{
+		{Alias: "airspace", Score: 3},
+		{Alias: "population", Score: 1},
+		{Code: "roads", Score: 0.5},
+	}
+
+	features := []HexFeature{
+		{
+			Hexes: map[h3.H3Index]bool{1: true, 2: true, 3: true},
+			Props: map[string]any{"layer": "airspace", "class": "B"},
+		},
+		{
+			Hexes: map[h3.H3Index]bool{2: true},
+			Props: map[string]any{"layer": "airspace", "class": "C"},
+		},
+		{
+			Hexes: map[h3.H3Index]bool{2: true, 3: true, 4: false},
+			Props: map[string]any{"layer": "population", "density": 1200.0},
+		},
+		{
+			Hexes: map[h3.H3Index]bool{3: true},
+			Props: map[string]any{"layer": "roads"},
+		},
+		{
+			Hexes: map[h3.H3Index]bool{1: true, 5: true},
+			Props: map[string]any{"layer": "weather"},
+		},
+		{
+			Hexes: map[h3.H3Index]bool{6: true},
+			Props: map[string]any{"prop": 1},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		reduce   ScoreReducer
+		expected map[h3.H3Index]float64
+	}{
+		{
+			name:     "default sums",
+			expected: map[h3.H3Index]float64{1: 3, 2: 4, 3: 4.5},
+		},
+		{
+			name:     "max",
+			reduce:   MaxScore,
+			expected: map[h3.H3Index]float64{1: 3, 2: 3, 3: 3},
+		},
+		{
+			name:     "weighted average",
+			reduce:   WeightedAverageScore(map[string]float64{"airspace": 2, "roads": 0}),
+			expected: map[h3.H3Index]float64{1: 3, 2: 7.0 / 3, 3: 7.0 / 3},
+		},
+		{
+			name: "custom",
+			reduce: func(scores map[string]float64) float64 {
+				return float64(len(scores))
+			},
+			expected: map[h3.H3Index]float64{1: 1, 2: 2, 3: 3},
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		cells := AggregateHexes(features, layers, LayerByProp("layer"), tc.reduce)
+
+		actual := map[h3.H3Index]float64{}
+		for hex, cell := range cells {
+			actual[hex] = cell.Score
+		}
+
+		t.Len(actual, len(tc.expected), tc.name)
+		t.InDeltaMapValues(tc.expected, actual, 1e-9, tc.name)
+	}
+
+	cells := AggregateHexes(features, layers, LayerByProp("layer"), nil)
+	t.Equal([]string{"airspace", "population"}, cells[2].Aliases())
+	t.Equal([]map[string]any{
+		{"layer": "airspace", "class": "B"},
+		{"layer": "airspace", "class": "C"},
+	}, cells[2].Props["airspace"])
+	t.Equal([]map[string]any{{"layer": "population", "density": 1200.0}}, cells[2].Props["population"])
+
+	byDensity := func(f HexFeature) (string, bool) {
+		_, ok := f.Props["density"]
+		return "population", ok
+	}
+
+	cells = AggregateHexes(features, layers, byDensity, nil)
+	t.Len(cells, 2)
+	t.Equal([]string{"population"}, cells[3].Aliases())
+	t.Equal(1.0, cells[3].Score)
+
+	// without a mapping, features name their layer in an "alias" prop
+	cells = AggregateHexes([]HexFeature{
+		{Hexes: map[h3.H3Index]bool{1: true}, Props: map[string]any{"alias": "roads"}},
+		{Hexes: map[h3.H3Index]bool{2: true}, Props: map[string]any{"layer": "roads"}},
+	}, layers, nil, nil)
+	t.Len(cells, 1)
+	t.Equal(0.5, cells[1].Score)
+}
@@ -0,0 +1,231 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned, without making a request, once an
+// endpoint family has used up its RateLimit.PerDay for the UTC day
+var ErrQuotaExceeded = errors.New("daily request quota exceeded")
+
+// Endpoint is a family of API endpoints that share a quota
+type Endpoint string
+
+const (
+	EndpointOAuth      Endpoint = "oauth"
+	EndpointSurface    Endpoint = "surface"
+	EndpointAdvisories Endpoint = "advisories"
+	EndpointOther      Endpoint = "other"
+)
+
+// endpointFor maps a request path to its endpoint family by its
+// first segment after the API version, e.g. /v4/advisories/{id}
+func endpointFor(path string) Endpoint {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 1 && isVersion(segments[0]) {
+		segments = segments[1:]
+	}
+
+	switch Endpoint(segments[0]) {
+	case EndpointOAuth:
+		return EndpointOAuth
+	case EndpointSurface:
+		return EndpointSurface
+	case EndpointAdvisories:
+		return EndpointAdvisories
+	default:
+		return EndpointOther
+	}
+}
+
+// isVersion reports whether a path segment is an API version like v4
+func isVersion(segment string) bool {
+	if len(segment) < 2 || segment[0] != 'v' {
+		return false
+	}
+
+	for _, r := range segment[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RateLimit is the quota for one endpoint family
+type RateLimit struct {
+	// PerSecond is the sustained request rate. Zero means unlimited
+	PerSecond float64
+
+	// Burst is how many requests may go out back to back. Defaults to 1
+	Burst int
+
+	// PerDay caps the requests made per UTC day. Zero means unlimited
+	PerDay int
+}
+
+// RateLimitStats is what a RateLimiter has seen of one endpoint family
+type RateLimitStats struct {
+	// Requests is how many requests were let through
+	Requests int
+
+	// Throttled is how many of those had to wait
+	Throttled int
+
+	// Waited is the total time requests spent waiting
+	Waited time.Duration
+}
+
+// RateLimiter is a token bucket per endpoint family that every request a
+// Client sends, retries included, has to pass through. Share one limiter
+// between every client using the same subscription key. The zero value
+// has no limits
+type RateLimiter struct {
+	// Limits holds the quota per endpoint family. Families
+	// without an entry aren't limited
+	Limits map[Endpoint]RateLimit
+
+	// OnWait, if set, is called whenever a request had to wait,
+	// e.g. to export the wait to a metrics system
+	OnWait func(ep Endpoint, waited time.Duration)
+
+	mu      sync.Mutex
+	buckets map[Endpoint]*bucket
+	stats   map[Endpoint]RateLimitStats
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	day    string
+	used   int
+}
+
+// Wait blocks until a request to ep is allowed, ctx is done,
+// or the daily quota for ep runs out
+func (l *RateLimiter) Wait(ctx context.Context, ep Endpoint) error {
+	if l == nil {
+		return nil
+	}
+
+	wait, err := l.reserve(ep)
+	if err != nil {
+		return err
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.cancel(ep)
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if l.OnWait != nil {
+			l.OnWait(ep, wait)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.stats[ep]
+	s.Requests++
+	if wait > 0 {
+		s.Throttled++
+		s.Waited += wait
+	}
+	l.stats[ep] = s
+	return nil
+}
+
+// Stats returns a snapshot of the limiter's counters per endpoint family
+func (l *RateLimiter) Stats() map[Endpoint]RateLimitStats {
+	if l == nil {
+		return map[Endpoint]RateLimitStats{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make(map[Endpoint]RateLimitStats, len(l.stats))
+	for ep, s := range l.stats {
+		stats[ep] = s
+	}
+
+	return stats
+}
+
+// reserve takes a token from ep's bucket, returning how long the caller
+// must wait for it. Reserving up front keeps concurrent callers in line
+func (l *RateLimiter) reserve(ep Endpoint) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.buckets == nil {
+		l.buckets = make(map[Endpoint]*bucket)
+		l.stats = make(map[Endpoint]RateLimitStats)
+	}
+
+	limit, ok := l.Limits[ep]
+	if !ok {
+		return 0, nil
+	}
+
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+
+	now := time.Now()
+	b, ok := l.buckets[ep]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[ep] = b
+	}
+
+	if day := now.UTC().Format("2006-01-02"); b.day != day {
+		b.day, b.used = day, 0
+	}
+
+	if limit.PerDay > 0 && b.used >= limit.PerDay {
+		return 0, fmt.Errorf("%s: %w", ep, ErrQuotaExceeded)
+	}
+	b.used++
+
+	if limit.PerSecond <= 0 {
+		return 0, nil
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * limit.PerSecond
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, nil
+	}
+
+	return time.Duration(-b.tokens / limit.PerSecond * float64(time.Second)), nil
+}
+
+// cancel hands back a reservation the caller gave up waiting on
+func (l *RateLimiter) cancel(ep Endpoint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[ep]; ok {
+		b.tokens++
+		b.used--
+	}
+}
@@ -0,0 +1,121 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpointFor(mainTest *testing.T) {
+	testCases := []struct {
+		arg      string
+		expected Endpoint
+	}{
+		{arg: "/v1/oauth/token", expected: EndpointOAuth},
+		{arg: "/v2/surface", expected: EndpointSurface},
+		{arg: "/v4/advisories", expected: EndpointAdvisories},
+		{arg: "/v4/advisories/abc/publish", expected: EndpointAdvisories},
+		{arg: "/v2/surface/layers", expected: EndpointSurface},
+		{arg: "/v4/advisories/surface-event", expected: EndpointAdvisories},
+		{arg: "/v4/oauth-clients", expected: EndpointOther},
+		{arg: "/v1/layers", expected: EndpointOther},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		t.Equal(tc.expected, endpointFor(tc.arg), tc.arg)
+	}
+}
+
+func TestRateLimiter(mainTest *testing.T) {
+	testCases := []struct {
+		name              string
+		limits            map[Endpoint]RateLimit
+		requests          int
+		timeout           time.Duration
+		expectedRequests  int
+		expectedThrottled int
+		minWaited         time.Duration
+		expectedErr       error
+	}{
+		{
+			name:             "no limits",
+			requests:         5,
+			expectedRequests: 5,
+		},
+		{
+			name:             "other families aren't affected",
+			limits:           map[Endpoint]RateLimit{EndpointAdvisories: {PerSecond: 1, PerDay: 1}},
+			requests:         5,
+			expectedRequests: 5,
+		},
+		{
+			name:              "per second limit with burst",
+			limits:            map[Endpoint]RateLimit{EndpointSurface: {PerSecond: 100, Burst: 2}},
+			requests:          5,
+			expectedRequests:  5,
+			expectedThrottled: 3,
+			minWaited:         15 * time.Millisecond,
+		},
+		{
+			name:             "daily quota",
+			limits:           map[Endpoint]RateLimit{EndpointSurface: {PerDay: 3}},
+			requests:         5,
+			expectedRequests: 3,
+			expectedErr:      ErrQuotaExceeded,
+		},
+		{
+			name:             "canceled while waiting",
+			limits:           map[Endpoint]RateLimit{EndpointSurface: {PerSecond: 0.001}},
+			timeout:          20 * time.Millisecond,
+			requests:         2,
+			expectedRequests: 1,
+			expectedErr:      context.DeadlineExceeded,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if tc.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+		}
+
+		hits := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			w.Write([]byte(`{"statusCode":200,"data":[]}`))
+		}))
+
+		limiter := &RateLimiter{Limits: tc.limits}
+		client := &Client{BaseURL: srv.URL, RateLimiter: limiter}
+
+		var actualErr error
+		for i := 0; i < tc.requests && actualErr == nil; i++ {
+			_, actualErr = client.Surface(ctx, exampleSurfaceReq())
+		}
+		srv.Close()
+		cancel()
+
+		t.True(errors.Is(actualErr, tc.expectedErr), tc.name)
+		t.Equal(tc.expectedRequests, hits, tc.name)
+
+		stats := limiter.Stats()[EndpointSurface]
+		t.Equal(tc.expectedRequests, stats.Requests, tc.name)
+		t.Equal(tc.expectedThrottled, stats.Throttled, tc.name)
+		t.GreaterOrEqual(int64(stats.Waited), int64(tc.minWaited), tc.name)
+	}
+}
+
+func TestNilRateLimiter(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	var limiter *RateLimiter
+	t.NoError(limiter.Wait(context.Background(), EndpointSurface))
+	t.Empty(limiter.Stats())
+}
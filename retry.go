@@ -0,0 +1,236 @@
+package asl
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryableStatus are the status codes retried when
+// RetryPolicy.RetryableStatus isn't set
+var DefaultRetryableStatus = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy describes how a Client retries requests that fail with a
+// network error or a retryable status. Backoff grows exponentially from
+// BaseDelay with full jitter, except when the response carries a
+// Retry-After header, which is honored as long as it fits in MaxDelay
+type RetryPolicy struct {
+	// MaxAttempts counts the first try, so values below 2 disable retries
+	MaxAttempts int
+
+	// BaseDelay is the upper bound of the first backoff, doubling on
+	// every subsequent attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps every backoff. A Retry-After longer than
+	// this ends the retries instead
+	MaxDelay time.Duration
+
+	// RetryableStatus defaults to DefaultRetryableStatus
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy returns a policy that makes up to four attempts,
+// backing off from 200ms up to 10s
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+
+	return p.MaxAttempts
+}
+
+// retryable reports whether req, having ended in resp or err, is worth
+// trying again. Context errors never are. Requests that aren't idempotent
+// are only retried when the server can't have acted on them: the
+// connection failed, or it answered 429 or 503
+func (p *RetryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	safe := idempotent(req)
+	if err != nil {
+		return !isContextErr(err) && (safe || dialFailed(err))
+	}
+
+	if !safe && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+
+	codes := p.RetryableStatus
+	if codes == nil {
+		codes = DefaultRetryableStatus
+	}
+
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// idempotent reports whether sending req twice is as good as sending it
+// once. Like net/http, an Idempotency-Key header, even a nil one that
+// isn't sent, marks a request as idempotent. Conditional writes aren't:
+// if the first attempt went through, the retry fails its precondition
+// and the caller can't tell that from someone else's write
+func idempotent(req *http.Request) bool {
+	if _, ok := req.Header[conditionalHeader]; ok {
+		return false
+	}
+
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// markIdempotent flags a POST that only reads, like a query, as safe to
+// retry, without sending anything extra
+func markIdempotent(req *http.Request) {
+	req.Header["Idempotency-Key"] = nil
+}
+
+// conditionalHeader is the nil header markConditional uses to flag a
+// request. Like a nil Idempotency-Key, it's never sent
+const conditionalHeader = "X-Asl-Conditional"
+
+// markConditional flags a write that only goes through while a
+// precondition holds, like a version check, as unsafe to retry
+func markConditional(req *http.Request) {
+	req.Header[conditionalHeader] = nil
+}
+
+// dialFailed reports whether err happened while connecting,
+// before any of the request was sent
+func dialFailed(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// backoff returns how long to wait before the attempt following attempt,
+// or false if the server asked for a longer wait than the policy allows
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return wait, p.MaxDelay == 0 || wait <= p.MaxDelay
+		}
+	}
+
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || (p.MaxDelay > 0 && ceiling > p.MaxDelay) {
+		ceiling = p.MaxDelay
+	}
+
+	if ceiling <= 0 {
+		return 0, true
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling))), true
+}
+
+// retryAfter parses a Retry-After header, which is either
+// a number of seconds or an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(header); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// rewindable reports whether req can be sent again, meaning it either
+// has no body or, like requests built by makeReq, knows how to recreate it
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind copies req with a fresh body so it can be sent again
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return next, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	next.Body = body
+	return next, nil
+}
+
+// do sends req, retrying per the client's retry policy. Every attempt
+// waits on the client's rate limiter first. Only the final response
+// is returned; earlier ones are drained and closed
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	ep := endpointFor(req.URL.Path)
+	for attempt := 1; ; attempt++ {
+		if err := c.RateLimiter.Wait(ctx, ep); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.HTTPClient.Do(req)
+		if attempt >= c.Retry.maxAttempts() || !rewindable(req) || !c.Retry.retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait, ok := c.Retry.backoff(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package asl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(mainTest *testing.T) {
+	fast := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	testCases := []struct {
+		name             string
+		policy           *RetryPolicy
+		statuses         []int
+		retryAfter       string
+		timeout          time.Duration
+		expectedAttempts int
+		expectedErr      string
+	}{
+		{
+			name:             "no policy means a single attempt",
+			statuses:         []int{503, 200},
+			expectedAttempts: 1,
+			expectedErr:      "unavailable",
+		},
+		{
+			name:             "recovers after retryable statuses",
+			policy:           fast,
+			statuses:         []int{503, 429, 200},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "gives up after max attempts",
+			policy:           fast,
+			statuses:         []int{502, 502, 502, 200},
+			expectedAttempts: 3,
+			expectedErr:      "unavailable",
+		},
+		{
+			name:             "client errors aren't retried",
+			policy:           fast,
+			statuses:         []int{400, 200},
+			expectedAttempts: 1,
+			expectedErr:      "unavailable",
+		},
+		{
+			name:             "custom retryable status set",
+			policy:           &RetryPolicy{MaxAttempts: 3, RetryableStatus: []int{500}},
+			statuses:         []int{500, 503, 200},
+			expectedAttempts: 2,
+			expectedErr:      "unavailable",
+		},
+		{
+			name:             "Retry-After within max delay is honored",
+			policy:           fast,
+			statuses:         []int{429, 200},
+			retryAfter:       "0",
+			expectedAttempts: 2,
+		},
+		{
+			name:             "Retry-After beyond max delay ends retries",
+			policy:           fast,
+			statuses:         []int{429, 200},
+			retryAfter:       "120",
+			expectedAttempts: 1,
+			expectedErr:      "unavailable",
+		},
+		{
+			name:             "canceled context stops the backoff",
+			policy:           &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour},
+			statuses:         []int{503, 200},
+			retryAfter:       "60",
+			timeout:          20 * time.Millisecond,
+			expectedAttempts: 1,
+			expectedErr:      "context deadline exceeded",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if tc.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+		}
+
+		attempts := 0
+		var bodies []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf, _ := io.ReadAll(r.Body)
+			bodies = append(bodies, string(buf))
+
+			status := tc.statuses[attempts]
+			attempts++
+			if tc.retryAfter != "" {
+				w.Header().Set("Retry-After", tc.retryAfter)
+			}
+
+			w.WriteHeader(status)
+			if status == 200 {
+				w.Write([]byte(`{"statusCode":200,"data":[]}`))
+				return
+			}
+
+			w.Write([]byte(`{"message":"unavailable"}`))
+		}))
+
+		client := &Client{BaseURL: srv.URL, Retry: tc.policy}
+		_, actualErr := client.Surface(ctx, exampleSurfaceReq())
+		srv.Close()
+		cancel()
+
+		t.Equal(tc.expectedAttempts, attempts, tc.name)
+		for _, body := range bodies {
+			t.Contains(body, `"resolution":9`, tc.name+" should resend the full body")
+		}
+
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		t.Nil(actualErr, tc.name)
+	}
+}
+
+func TestRetryIdempotency(mainTest *testing.T) {
+	fast := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	testCases := []struct {
+		name             string
+		call             func(c *Client) error
+		statuses         []int
+		expectedAttempts int
+	}{
+		{
+			name: "create isn't retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.CreateAdvisory(context.Background(), &Advisory{Name: "new"})
+				return err
+			},
+			statuses:         []int{502, 200},
+			expectedAttempts: 1,
+		},
+		{
+			name: "create is retried when throttled",
+			call: func(c *Client) error {
+				_, err := c.CreateAdvisory(context.Background(), &Advisory{Name: "new"})
+				return err
+			},
+			statuses:         []int{429, 503, 200},
+			expectedAttempts: 3,
+		},
+		{
+			name: "get is retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.GetAdvisory(context.Background(), "a1")
+				return err
+			},
+			statuses:         []int{502, 200},
+			expectedAttempts: 2,
+		},
+		{
+			name: "queries are retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.QueryAdvisoriesByGeom(context.Background(), &QueryAdvisoriesArgs{})
+				return err
+			},
+			statuses:         []int{504, 200},
+			expectedAttempts: 2,
+		},
+		{
+			name: "unconditional update is retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1"})
+				return err
+			},
+			statuses:         []int{502, 200},
+			expectedAttempts: 2,
+		},
+		{
+			name: "conditional update isn't retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1", OVN: "ovn-1", Version: 1})
+				return err
+			},
+			statuses:         []int{502, 200},
+			expectedAttempts: 1,
+		},
+		{
+			name: "update conditional on just the version isn't retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.UpdateAdvisory(context.Background(), &Advisory{ID: "a1", Version: 1})
+				return err
+			},
+			statuses:         []int{502, 412},
+			expectedAttempts: 1,
+		},
+		{
+			name: "delete conditional on just the version isn't retried after a bad gateway",
+			call: func(c *Client) error {
+				_, err := c.DeleteAdvisoryVersion(context.Background(), &Advisory{ID: "a1", Version: 1})
+				return err
+			},
+			statuses:         []int{502, 412},
+			expectedAttempts: 1,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		attempts := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := tc.statuses[attempts]
+			attempts++
+
+			w.WriteHeader(status)
+			if status == 200 {
+				w.Write([]byte(`{"statusCode":200,"data":null}`))
+			}
+		}))
+
+		err := tc.call(&Client{BaseURL: srv.URL, Retry: fast})
+		srv.Close()
+
+		t.Equal(tc.expectedAttempts, attempts, tc.name)
+		t.Equal(tc.expectedAttempts == len(tc.statuses), err == nil, tc.name)
+	}
+}
+
+func TestRetryAfter(mainTest *testing.T) {
+	testCases := []struct {
+		name       string
+		arg        string
+		expected   time.Duration
+		expectedOK bool
+	}{
+		{name: "missing"},
+		{name: "seconds", arg: "3", expected: 3 * time.Second, expectedOK: true},
+		{name: "date in the past", arg: "Wed, 21 Oct 2015 07:28:00 GMT", expectedOK: true},
+		{name: "garbage", arg: "soon"},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, actualOK := retryAfter(tc.arg)
+		t.Equal(tc.expected, actual, tc.name)
+		t.Equal(tc.expectedOK, actualOK, tc.name)
+	}
+}
@@ -2,17 +2,35 @@ package asl
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"strings"
 
 	"github.com/peterstace/simplefeatures/geom"
 )
 
+// MaxResolution is the finest h3 resolution a surface request can ask for
+const MaxResolution = 15
+
+// Limits on the area a single surface request may cover. Requests
+// outside them are rejected by Validate before they're sent
+const (
+	maxSurfaceVertices = 10_000
+
+	// maxSurfaceArea is in square kilometres
+	maxSurfaceArea = 10_000.0
+)
+
 type Layer struct {
 	Alias  string   `json:"alias"`
 	Fields []string `json:"fields"`
 	Code   string   `json:"code"`
-	Where  []any    `json:"where"`
-	Score  float64  `json:"score"`
+
+	// Where filters the layer's features. It's sent as it is;
+	// SetWhere can build one from a Cond
+	Where []any   `json:"where"`
+	Score float64 `json:"score"`
 }
 
 type SurfaceReq struct {
@@ -22,16 +40,162 @@ type SurfaceReq struct {
 	// Layers you want to query
 	Layers []Layer `json:"layers"`
 
-	// Resolution you want to use for hexagon indexing. The highest
-	// possible is 16, but
+	// Resolution you want to use for hexagon indexing, from 0
+	// up to MaxResolution
 	Resolution uint8 `json:"resolution"`
 }
 
-func (c Client) Surface(ctx context.Context, req *SurfaceReq) (*Resp[[]HexFeature], error) {
+// ValidationError lists every problem Validate found with a request.
+// Use errors.As to get at the individual problems
+type ValidationError []error
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the request before it's sent: the resolution is in
+// range, the geometry is a valid polygon or multipolygon of at most
+// 10,000 vertices and 10,000 km², and every layer has a code and, if
+// it's aliased, an alias no other layer uses. Where clauses aren't
+// checked. Every problem is listed in the returned ValidationError
+func (r *SurfaceReq) Validate() error {
+	return r.validate(true)
+}
+
+// validate is Validate, optionally skipping the size
+// limits for requests that get split up anyway
+func (r *SurfaceReq) validate(checkSize bool) error {
+	var errs ValidationError
+	if r.Resolution > MaxResolution {
+		errs = append(errs, fmt.Errorf("resolution %d is above %d", r.Resolution, MaxResolution))
+	}
+
+	errs = append(errs, validateSurfaceGeometry(r.Geometry, checkSize)...)
+
+	if len(r.Layers) == 0 {
+		errs = append(errs, fmt.Errorf("no layers to query"))
+	}
+
+	aliases := make(map[string]int, len(r.Layers))
+	for i := range r.Layers {
+		l := &r.Layers[i]
+		if l.Code == "" {
+			errs = append(errs, fmt.Errorf("layer %d has no code", i))
+		}
+
+		if l.Alias == "" {
+			continue
+		}
+
+		if first, ok := aliases[l.Alias]; ok {
+			errs = append(errs, fmt.Errorf("layers %d and %d are both aliased %q", first, i, l.Alias))
+		} else {
+			aliases[l.Alias] = i
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateSurfaceGeometry(g geom.Geometry, checkSize bool) []error {
+	if g.IsEmpty() {
+		return []error{fmt.Errorf("geometry is empty")}
+	}
+
+	if t := g.Type(); t != geom.TypePolygon && t != geom.TypeMultiPolygon {
+		return []error{fmt.Errorf("geometry is a %s, not a Polygon or MultiPolygon", t)}
+	}
+
+	var errs []error
+
+	// geometries built without validation (e.g. with geom.DisableAllValidations)
+	// can be invalid, so rebuild it with validation on
+	if _, err := geom.UnmarshalWKB(g.AsBinary()); err != nil {
+		errs = append(errs, fmt.Errorf("geometry is invalid: %w", err))
+	}
+
+	if !checkSize {
+		return errs
+	}
+
+	if n := g.DumpCoordinates().Length(); n > maxSurfaceVertices {
+		errs = append(errs, fmt.Errorf("geometry has %d vertices, more than %d", n, maxSurfaceVertices))
+	}
+
+	if area := sphericalArea(g) / 1e6; area > maxSurfaceArea {
+		errs = append(errs, fmt.Errorf("geometry covers %.0f km², more than %.0f", area, maxSurfaceArea))
+	}
+
+	return errs
+}
+
+// earthRadius is the WGS84 equatorial radius in metres
+const earthRadius = 6378137.0
+
+// sphericalArea approximates the area of a lon/lat polygon
+// or multipolygon in square metres
+func sphericalArea(g geom.Geometry) float64 {
+	mp, ok := asMultiPolygon(g)
+	if !ok {
+		return 0
+	}
+
+	var area float64
+	for i := 0; i < mp.NumPolygons(); i++ {
+		for j, ring := range mp.PolygonN(i).Coordinates() {
+			if j == 0 {
+				area += ringArea(ring)
+			} else {
+				area -= ringArea(ring)
+			}
+		}
+	}
+
+	return area
+}
+
+// asMultiPolygon converts polygons and multipolygons to multipolygons
+func asMultiPolygon(g geom.Geometry) (geom.MultiPolygon, bool) {
+	if p, ok := g.AsPolygon(); ok {
+		return p.AsMultiPolygon(), true
+	}
+
+	return g.AsMultiPolygon()
+}
+
+// ringArea is the area enclosed by a closed ring on a sphere
+func ringArea(ring geom.Sequence) float64 {
+	rad := math.Pi / 180
+	var sum float64
+	for i := 0; i+1 < ring.Length(); i++ {
+		p1, p2 := ring.GetXY(i), ring.GetXY(i+1)
+		sum += (p2.X - p1.X) * rad * (2 + math.Sin(p1.Y*rad) + math.Sin(p2.Y*rad))
+	}
+
+	return math.Abs(sum * earthRadius * earthRadius / 2)
+}
+
+// Surface scores the hexes covering req.Geometry against req.Layers.
+// Requests that fail Validate are rejected before anything is sent
+func (c *Client) Surface(ctx context.Context, req *SurfaceReq) (*Resp[[]HexFeature], error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
 	httpReq, err := c.makeJSONReq(ctx, http.MethodPost, "/v2/surface", req)
 	if err != nil {
 		return nil, err
 	}
+	markIdempotent(httpReq)
 
-	return apiReq[[]HexFeature](&c.HTTPClient, httpReq)
+	return authedReq[[]HexFeature](c, httpReq)
 }
@@ -0,0 +1,116 @@
+package asl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/peterstace/simplefeatures/geom"
+)
+
+// LayerField is a field a surface layer's features carry
+type LayerField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// LayerInfo describes a layer the surface endpoint can score against
+type LayerInfo struct {
+	Code        string       `json:"code"`
+	Description string       `json:"description"`
+	Fields      []LayerField `json:"fields"`
+
+	// Resolutions are the hexagon resolutions the layer supports
+	Resolutions []uint8 `json:"resolutions"`
+
+	DefaultScore float64 `json:"defaultScore"`
+
+	// Coverage is the area the layer has data for. Nil means everywhere
+	Coverage *geom.Geometry `json:"coverage"`
+}
+
+// ListLayers fetches the catalog of surface layers
+func (c *Client) ListLayers(ctx context.Context) (*Resp[[]LayerInfo], error) {
+	req, err := c.makeReq(ctx, http.MethodGet, "/v2/surface/layers", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return authedReq[[]LayerInfo](c, req)
+}
+
+// DescribeLayer fetches the metadata of a single surface layer
+func (c *Client) DescribeLayer(ctx context.Context, code string) (*Resp[LayerInfo], error) {
+	if code == "" {
+		return nil, fmt.Errorf("layer code is required")
+	}
+
+	req, err := c.makeReq(ctx, http.MethodGet, "/v2/surface/layers/"+url.PathEscape(code), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return authedReq[LayerInfo](c, req)
+}
+
+// Field returns the field called name
+func (info LayerInfo) Field(name string) (LayerField, bool) {
+	for _, f := range info.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+
+	return LayerField{}, false
+}
+
+// SupportsResolution reports whether the layer can be queried at
+// res. Layers that don't list their resolutions support them all
+func (info LayerInfo) SupportsResolution(res uint8) bool {
+	if len(info.Resolutions) == 0 {
+		return true
+	}
+
+	for _, r := range info.Resolutions {
+		if r == res {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Layer builds a query for the layer, aliased as alias (defaulting
+// to the layer's code) and scored with its default score. fields
+// default to every field the layer has; asking for one it doesn't
+// have is an error
+func (info LayerInfo) Layer(alias string, fields ...string) (Layer, error) {
+	if info.Code == "" {
+		return Layer{}, fmt.Errorf("layer code is required")
+	}
+
+	if alias == "" {
+		alias = info.Code
+	}
+
+	for _, name := range fields {
+		if _, ok := info.Field(name); !ok {
+			return Layer{}, fmt.Errorf("layer %q has no field %q", info.Code, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		for _, f := range info.Fields {
+			fields = append(fields, f.Name)
+		}
+	}
+
+	return Layer{
+		Alias:  alias,
+		Code:   info.Code,
+		Fields: fields,
+		Score:  info.DefaultScore,
+	}, nil
+}
@@ -0,0 +1,125 @@
+package asl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const airspaceLayerJSON = `{
+	"code": "class/airspace",
+	"description": "Controlled airspace",
+	"fields": [
+		{"name": "class", "type": "string", "description": "Airspace class"},
+		{"name": "floor", "type": "number", "description": "Floor in feet"}
+	],
+	"resolutions": [7, 8, 9],
+	"defaultScore": 2.5,
+	"coverage": {"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1],[0,1],[0,0]]]}
+}`
+
+func TestLayerCatalog(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.EscapedPath())
+
+		if r.URL.Path == "/v2/surface/layers" {
+			w.Write([]byte(`{"statusCode":200,"data":[` + airspaceLayerJSON + `,{"code":"population","coverage":null}]}`))
+			return
+		}
+
+		w.Write([]byte(`{"statusCode":200,"data":` + airspaceLayerJSON + `}`))
+	}))
+	defer srv.Close()
+
+	client := &Client{BaseURL: srv.URL}
+
+	list, err := client.ListLayers(context.Background())
+	if t.NoError(err) && t.Len(list.Data, 2) {
+		t.Equal("class/airspace", list.Data[0].Code)
+		t.Equal("population", list.Data[1].Code)
+		t.Nil(list.Data[1].Coverage)
+	}
+
+	desc, err := client.DescribeLayer(context.Background(), "class/airspace")
+	if t.NoError(err) {
+		info := desc.Data
+		t.Equal("Controlled airspace", info.Description)
+		t.Equal([]LayerField{
+			{Name: "class", Type: "string", Description: "Airspace class"},
+			{Name: "floor", Type: "number", Description: "Floor in feet"},
+		}, info.Fields)
+		t.Equal([]uint8{7, 8, 9}, info.Resolutions)
+		t.Equal(2.5, info.DefaultScore)
+		if t.NotNil(info.Coverage) {
+			t.Equal("Polygon", info.Coverage.Type().String())
+		}
+	}
+
+	_, err = client.DescribeLayer(context.Background(), "")
+	t.EqualError(err, "layer code is required")
+
+	t.Equal([]string{"GET /v2/surface/layers", "GET /v2/surface/layers/class%2Fairspace"}, paths)
+}
+
+func TestLayerInfoLayer(mainTest *testing.T) {
+	info := LayerInfo{
+		Code:         "airspace",
+		Fields:       []LayerField{{Name: "class"}, {Name: "floor"}},
+		Resolutions:  []uint8{8},
+		DefaultScore: 3,
+	}
+
+	testCases := []struct {
+		name        string
+		info        LayerInfo
+		alias       string
+		fields      []string
+		expected    Layer
+		expectedErr string
+	}{
+		{
+			name:     "defaults",
+			info:     info,
+			expected: Layer{Alias: "airspace", Code: "airspace", Fields: []string{"class", "floor"}, Score: 3},
+		},
+		{
+			name:     "alias and fields",
+			info:     info,
+			alias:    "controlled",
+			fields:   []string{"floor"},
+			expected: Layer{Alias: "controlled", Code: "airspace", Fields: []string{"floor"}, Score: 3},
+		},
+		{
+			name:        "unknown field",
+			info:        info,
+			fields:      []string{"height"},
+			expectedErr: `layer "airspace" has no field "height"`,
+		},
+		{
+			name:        "no code",
+			expectedErr: "layer code is required",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, err := tc.info.Layer(tc.alias, tc.fields...)
+		if tc.expectedErr != "" {
+			t.EqualError(err, tc.expectedErr, tc.name)
+			continue
+		}
+
+		t.NoError(err, tc.name)
+		t.Equal(tc.expected, actual, tc.name)
+	}
+
+	t.True(info.SupportsResolution(8))
+	t.False(info.SupportsResolution(9))
+	t.True(LayerInfo{}.SupportsResolution(9))
+}
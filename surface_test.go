@@ -0,0 +1,139 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/peterstace/simplefeatures/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+// exampleSurfaceReq is a small, valid surface request
+func exampleSurfaceReq() *SurfaceReq {
+	return &SurfaceReq{
+		Geometry:   mustWKT("POLYGON((-93.3 44.9,-93.2 44.9,-93.2 45,-93.3 45,-93.3 44.9))"),
+		Layers:     []Layer{{Code: "airspace"}},
+		Resolution: 9,
+	}
+}
+
+func TestSurfaceReqValidate(mainTest *testing.T) {
+	bowtie, err := geom.UnmarshalWKT("POLYGON((0 0,0.1 0.1,0.1 0,0 0.1,0 0))", geom.DisableAllValidations)
+	if err != nil {
+		panic(err)
+	}
+
+	// a circle with a vertex too many, counting the closing one
+	coords := []string{}
+	for i := 0; i < maxSurfaceVertices; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(maxSurfaceVertices)
+		coords = append(coords, fmt.Sprintf("%.8f %.8f", 0.01*math.Cos(angle), 0.01*math.Sin(angle)))
+	}
+	circle := mustWKT("POLYGON((" + strings.Join(append(coords, coords[0]), ",") + "))")
+
+	testCases := []struct {
+		name     string
+		mutate   func(r *SurfaceReq)
+		expected []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(r *SurfaceReq) {},
+		},
+		{
+			name: "multipolygon",
+			mutate: func(r *SurfaceReq) {
+				r.Geometry = mustWKT("MULTIPOLYGON(((0 0,0.1 0,0.1 0.1,0 0)),((1 1,1.1 1,1.1 1.1,1 1)))")
+			},
+		},
+		{
+			name: "everything wrong",
+			mutate: func(r *SurfaceReq) {
+				r.Resolution = 16
+				r.Geometry = geom.Geometry{}
+				r.Layers = nil
+			},
+			expected: []string{"resolution 16 is above 15", "geometry is empty", "no layers to query"},
+		},
+		{
+			name:     "point",
+			mutate:   func(r *SurfaceReq) { r.Geometry = examplePoint.AsGeometry() },
+			expected: []string{"geometry is a Point, not a Polygon or MultiPolygon"},
+		},
+		{
+			name:     "self-intersecting",
+			mutate:   func(r *SurfaceReq) { r.Geometry = bowtie },
+			expected: []string{"geometry is invalid: failed geometry constraint: polygon ring not simple"},
+		},
+		{
+			name:     "too many vertices",
+			mutate:   func(r *SurfaceReq) { r.Geometry = circle },
+			expected: []string{fmt.Sprintf("geometry has %d vertices, more than %d", maxSurfaceVertices+1, maxSurfaceVertices)},
+		},
+		{
+			name:     "too big",
+			mutate:   func(r *SurfaceReq) { r.Geometry = mustWKT("POLYGON((-100 40,-90 40,-90 45,-100 45,-100 40))") },
+			expected: []string{"geometry covers 456673 km², more than 10000"},
+		},
+		{
+			name: "bad layers",
+			mutate: func(r *SurfaceReq) {
+				r.Layers = []Layer{
+					{Alias: "a", Code: "airspace"},
+					{Alias: "b"},
+					{Code: "airspace"},
+					{Alias: "a", Code: "airspace"},
+				}
+			},
+			expected: []string{
+				"layer 1 has no code",
+				`layers 0 and 3 are both aliased "a"`,
+			},
+		},
+		{
+			name: "layers without aliases",
+			mutate: func(r *SurfaceReq) {
+				r.Layers = []Layer{{Code: "airspace"}, {Code: "airspace"}}
+			},
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		req := exampleSurfaceReq()
+		tc.mutate(req)
+
+		err := req.Validate()
+		if len(tc.expected) == 0 {
+			t.NoError(err, tc.name)
+			continue
+		}
+
+		var verr ValidationError
+		if !t.True(errors.As(err, &verr), tc.name) {
+			continue
+		}
+
+		var actual []string
+		for _, e := range verr {
+			actual = append(actual, e.Error())
+		}
+
+		t.Equal(tc.expected, actual, tc.name)
+		t.Equal("invalid request: "+strings.Join(tc.expected, "; "), err.Error(), tc.name)
+	}
+}
+
+func TestSurfaceValidates(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	// nothing is listening, so getting past validation would be a different error
+	client := &Client{BaseURL: "http://127.0.0.1:0"}
+	_, err := client.Surface(context.Background(), &SurfaceReq{})
+
+	t.EqualError(err, "invalid request: geometry is empty; no layers to query")
+}
@@ -0,0 +1,501 @@
+package asl
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/peterstace/simplefeatures/geom"
+	"github.com/uber/h3-go/v3"
+)
+
+const (
+	// DefaultTileDepth is how many resolutions coarser than the request
+	// the cells SurfaceTiled splits it along are
+	DefaultTileDepth = 3
+
+	// DefaultTileWorkers is how many chunks SurfaceTiled sends at once
+	DefaultTileWorkers = 4
+)
+
+// TileOptions tune SurfaceTiled. The zero value uses the defaults
+type TileOptions struct {
+	// Depth is how many resolutions coarser than the request the cells
+	// the area is cut along are, e.g. a depth of 3 at resolution 9 cuts
+	// it along resolution 6 cells. Cells too big for Surface are never
+	// used, and pieces still too big are cut again along finer cells.
+	// Defaults to DefaultTileDepth
+	Depth int
+
+	// Workers caps how many chunks are in flight. Defaults to DefaultTileWorkers
+	Workers int
+
+	// FeatureKey, if set, merges the features of different chunks it
+	// gives the same key, e.g. the layer they came from. Without it each
+	// chunk's features are kept apart, since nothing in a feature says
+	// which features of the other chunks it continues
+	FeatureKey func(HexFeature) string
+}
+
+func (o *TileOptions) depth() int {
+	if o == nil || o.Depth <= 0 {
+		return DefaultTileDepth
+	}
+
+	return o.Depth
+}
+
+func (o *TileOptions) workers() int {
+	if o == nil || o.Workers <= 0 {
+		return DefaultTileWorkers
+	}
+
+	return o.Workers
+}
+
+func (o *TileOptions) featureKey() func(HexFeature) string {
+	if o == nil {
+		return nil
+	}
+
+	return o.FeatureKey
+}
+
+// surfaceChunk is the piece of a request's geometry inside a cell
+type surfaceChunk struct {
+	cell     h3.H3Index
+	geometry geom.Geometry
+}
+
+// SurfaceTiled is Surface for areas too big for a single request. The
+// geometry is cut along the h3 cells a few resolutions coarser than the
+// request that it overlaps (see TileOptions), down to pieces that fit
+// Surface's limits, and each piece is sent as its own request. The
+// pieces cover the geometry without overlapping, so together they get
+// back the same hexes a single request would. A hex on the edge of two
+// pieces comes back for both; it's kept under the first piece only, so
+// each hex shows up once. The pieces are sent concurrently and their
+// features merged as TileOptions.FeatureKey says. Each piece is held to
+// Surface's limits, but the whole request isn't. opts may be nil
+func (c *Client) SurfaceTiled(ctx context.Context, req *SurfaceReq, opts *TileOptions) (*Resp[[]HexFeature], error) {
+	if err := req.validate(false); err != nil {
+		return nil, err
+	}
+
+	// no coarser than cells that could fit in a single request
+	parentRes := int(req.Resolution) - opts.depth()
+	if parentRes < 0 {
+		parentRes = 0
+	}
+
+	for parentRes < MaxResolution && h3.HexAreaKm2(parentRes) > maxSurfaceArea {
+		parentRes++
+	}
+
+	chunks, err := surfaceChunks(req.Geometry, parentRes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chunks) == 0 {
+		return c.Surface(ctx, req)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failure  error
+		jobs     = make(chan int)
+		results  = make([]*Resp[[]HexFeature], len(chunks))
+	)
+
+	for w := 0; w < opts.workers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				chunkReq := *req
+				chunkReq.Geometry = chunks[i].geometry
+
+				resp, err := c.Surface(ctx, &chunkReq)
+				if err != nil {
+					failOnce.Do(func() {
+						failure = fmt.Errorf("surface chunk %s: %w", h3.ToString(chunks[i].cell), err)
+						cancel()
+					})
+					continue
+				}
+
+				results[i] = resp
+			}
+		}()
+	}
+
+feed:
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	if failure != nil {
+		return nil, failure
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := &Resp[[]HexFeature]{Status: 200}
+	pages := make([][]HexFeature, len(results))
+	for i, resp := range results {
+		pages[i] = resp.Data
+		if i == 0 {
+			merged.Status, merged.Msg = resp.Status, resp.Msg
+		}
+	}
+
+	merged.Data = mergeHexFeatures(pages, opts.featureKey())
+	return merged, nil
+}
+
+// mergeHexFeatures combines the chunks' features, keeping each hex
+// only under the first chunk whose features hold it. Features key gives
+// the same key are merged, in the order they first appear; without a
+// key every feature is kept
+func mergeHexFeatures(pages [][]HexFeature, key func(HexFeature) string) []HexFeature {
+	owner := map[h3.H3Index]int{}
+	for i, page := range pages {
+		for _, f := range page {
+			for hex, in := range f.Hexes {
+				if _, ok := owner[hex]; in && !ok {
+					owner[hex] = i
+				}
+			}
+		}
+	}
+
+	merged := []HexFeature{}
+	byKey := map[string]int{}
+	for i, page := range pages {
+		for _, f := range page {
+			hexes := map[h3.H3Index]bool{}
+			for hex, in := range f.Hexes {
+				if in && owner[hex] == i {
+					hexes[hex] = true
+				}
+			}
+
+			if key == nil {
+				merged = append(merged, HexFeature{Hexes: hexes, Props: f.Props})
+				continue
+			}
+
+			k := key(f)
+			j, ok := byKey[k]
+			if !ok {
+				byKey[k] = len(merged)
+				merged = append(merged, HexFeature{Hexes: hexes, Props: f.Props})
+				continue
+			}
+
+			for hex := range hexes {
+				merged[j].Hexes[hex] = true
+			}
+		}
+	}
+
+	return merged
+}
+
+// surfaceChunks cuts g along the cells at res it overlaps, and cuts
+// the pieces still too big for Surface again along finer cells
+func surfaceChunks(g geom.Geometry, res int) ([]surfaceChunk, error) {
+	outlines, err := cellsOverlapping(g, res)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []surfaceChunk
+	for _, cell := range sortedHexes(outlines) {
+		piece, err := geom.Intersection(g, outlines[cell])
+		if err != nil {
+			return nil, fmt.Errorf("cutting surface chunk %s: %w", h3.ToString(cell), err)
+		}
+
+		// cells that only touch g leave nothing to send
+		piece, ok := polygonal(piece)
+		if !ok {
+			continue
+		}
+
+		errs := validateSurfaceGeometry(piece, true)
+		if len(errs) == 0 {
+			chunks = append(chunks, surfaceChunk{cell: cell, geometry: piece})
+			continue
+		}
+
+		if res == MaxResolution {
+			return nil, fmt.Errorf("surface chunk %s: %w", h3.ToString(cell), ValidationError(errs))
+		}
+
+		finer, err := surfaceChunks(piece, res+1)
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, finer...)
+	}
+
+	return chunks, nil
+}
+
+// cellsOverlapping outlines the cells at res that g overlaps. They're
+// found by walking out from the cells around each polygon's first
+// vertex, so even geometries too thin to hold a cell's centre are found
+func cellsOverlapping(g geom.Geometry, res int) (map[h3.H3Index]geom.Geometry, error) {
+	seen := map[h3.H3Index]bool{}
+
+	var queue []h3.H3Index
+	mp, _ := asMultiPolygon(g)
+	for i := 0; i < mp.NumPolygons(); i++ {
+		xy := mp.PolygonN(i).ExteriorRing().Coordinates().GetXY(0)
+
+		// the neighbours too, in case the vertex is on the cell's edge
+		for _, cell := range h3.KRing(h3.FromGeo(h3.GeoCoord{Latitude: xy.Y, Longitude: xy.X}, res), 1) {
+			if !seen[cell] {
+				seen[cell] = true
+				queue = append(queue, cell)
+			}
+		}
+	}
+
+	outlines := map[h3.H3Index]geom.Geometry{}
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+
+		outline, err := hexOutline(map[h3.H3Index]bool{cell: true})
+		if err != nil {
+			return nil, fmt.Errorf("outlining cell %s: %w", h3.ToString(cell), err)
+		}
+
+		if !geom.Intersects(outline, g) {
+			continue
+		}
+
+		outlines[cell] = outline
+		for _, n := range h3.KRing(cell, 1) {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return outlines, nil
+}
+
+// sortedHexes lists the keys of a map of hexes in order
+func sortedHexes[V any](hexes map[h3.H3Index]V) []h3.H3Index {
+	sorted := make([]h3.H3Index, 0, len(hexes))
+	for hex := range hexes {
+		sorted = append(sorted, hex)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// hexOutline is the exact outline of a set of hexes as a lon/lat
+// multipolygon, cut in two where it crosses the antimeridian
+func hexOutline(hexes map[h3.H3Index]bool) (geom.Geometry, error) {
+	var polys []geom.Polygon
+	for _, group := range connectedHexes(hexes) {
+		p, unwrapped, err := linkedPolygon(h3.SetToLinkedGeo(group))
+		if err != nil {
+			return geom.Geometry{}, err
+		}
+
+		if !unwrapped {
+			polys = append(polys, p)
+			continue
+		}
+
+		parts, err := cutAntimeridian(p)
+		if err != nil {
+			return geom.Geometry{}, err
+		}
+
+		polys = append(polys, parts...)
+	}
+
+	mp, err := geom.NewMultiPolygon(polys)
+	if err != nil {
+		return geom.Geometry{}, err
+	}
+
+	return mp.AsGeometry(), nil
+}
+
+// connectedHexes splits hexes into groups of neighbours, since
+// h3.SetToLinkedGeo only returns the first polygon of an outline
+func connectedHexes(hexes map[h3.H3Index]bool) [][]h3.H3Index {
+	seen := map[h3.H3Index]bool{}
+
+	var groups [][]h3.H3Index
+	for _, start := range sortedHexes(hexes) {
+		if seen[start] {
+			continue
+		}
+
+		seen[start] = true
+		group := []h3.H3Index{start}
+		for i := 0; i < len(group); i++ {
+			for _, n := range h3.KRing(group[i], 1) {
+				if hexes[n] && !seen[n] {
+					seen[n] = true
+					group = append(group, n)
+				}
+			}
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// linkedPolygon converts an h3 outline, an outer loop followed by its
+// holes, to a polygon. An outline crossing the antimeridian, seen as
+// an edge spanning more than 180° of longitude, is unwrapped to carry
+// on past 180°, which the second result reports
+func linkedPolygon(outline h3.LinkedGeoPolygon) (geom.Polygon, bool, error) {
+	var loops [][]float64
+	crosses := false
+	for loop := outline.First; loop != nil; loop = loop.Next {
+		var coords []float64
+		for c := loop.First; c != nil; c = c.Next {
+			coords = append(coords, c.Vertex.Longitude, c.Vertex.Latitude)
+		}
+
+		if len(coords) == 0 {
+			continue
+		}
+
+		coords = append(coords, coords[0], coords[1])
+		for i := 2; i < len(coords); i += 2 {
+			crosses = crosses || math.Abs(coords[i]-coords[i-2]) > 180
+		}
+
+		loops = append(loops, coords)
+	}
+
+	rings := make([]geom.LineString, len(loops))
+	for i, coords := range loops {
+		if crosses {
+			for j := 0; j < len(coords); j += 2 {
+				if coords[j] < 0 {
+					coords[j] += 360
+				}
+			}
+		}
+
+		ring, err := geom.NewLineString(geom.NewSequence(coords, geom.DimXY))
+		if err != nil {
+			return geom.Polygon{}, false, err
+		}
+
+		rings[i] = ring
+	}
+
+	p, err := geom.NewPolygon(rings)
+	return p, crosses, err
+}
+
+// cutAntimeridian cuts a polygon unwrapped past 180° of longitude into
+// its parts either side of the antimeridian, in the usual range
+func cutAntimeridian(p geom.Polygon) ([]geom.Polygon, error) {
+	var parts []geom.Polygon
+	for _, side := range []struct{ minX, maxX, shift float64 }{{0, 180, 0}, {180, 360, -360}} {
+		box, err := geom.NewEnvelope([]geom.XY{{X: side.minX, Y: -90}, {X: side.maxX, Y: 90}})
+		if err != nil {
+			return nil, err
+		}
+
+		part, err := geom.Intersection(p.AsGeometry(), box.AsGeometry())
+		if err != nil {
+			return nil, err
+		}
+
+		part, ok := polygonal(part)
+		if !ok {
+			continue
+		}
+
+		shift := side.shift
+		part, err = part.TransformXY(func(xy geom.XY) geom.XY { return geom.XY{X: xy.X + shift, Y: xy.Y} })
+		if err != nil {
+			return nil, err
+		}
+
+		mp, _ := asMultiPolygon(part)
+		for i := 0; i < mp.NumPolygons(); i++ {
+			parts = append(parts, mp.PolygonN(i))
+		}
+	}
+
+	return parts, nil
+}
+
+// polygonal drops everything but the polygons from g, which overlay
+// operations can leave lines and points in where shapes only touch
+func polygonal(g geom.Geometry) (geom.Geometry, bool) {
+	if g.IsEmpty() {
+		return g, false
+	}
+
+	switch g.Type() {
+	case geom.TypePolygon, geom.TypeMultiPolygon:
+		return g, true
+	case geom.TypeGeometryCollection:
+	default:
+		return g, false
+	}
+
+	gc, _ := g.AsGeometryCollection()
+
+	var polys []geom.Polygon
+	for i := 0; i < gc.NumGeometries(); i++ {
+		part, ok := polygonal(gc.GeometryN(i))
+		if !ok {
+			continue
+		}
+
+		mp, _ := asMultiPolygon(part)
+		for j := 0; j < mp.NumPolygons(); j++ {
+			polys = append(polys, mp.PolygonN(j))
+		}
+	}
+
+	if len(polys) == 0 {
+		return g, false
+	}
+
+	mp, err := geom.NewMultiPolygon(polys)
+	if err != nil {
+		return g, false
+	}
+
+	return mp.AsGeometry(), true
+}
@@ -0,0 +1,338 @@
+package asl
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/peterstace/simplefeatures/geom"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/h3-go/v3"
+)
+
+// coveringHexes is what the test servers take the API to return: the
+// hexes at res whose outlines overlap g. It's brute force over g's
+// envelope, grown by a couple of hex edges, so it doesn't share the way
+// SurfaceTiled finds cells
+func coveringHexes(g geom.Geometry, res int) map[h3.H3Index]bool {
+	margin := 2 * h3.EdgeLengthKm(res) / 111
+
+	covering := map[h3.H3Index]bool{}
+	mp, _ := asMultiPolygon(g)
+	for i := 0; i < mp.NumPolygons(); i++ {
+		min, max, _ := mp.PolygonN(i).Envelope().MinMaxXYs()
+		lonMargin := margin / math.Cos(math.Max(math.Abs(min.Y), math.Abs(max.Y))*math.Pi/180)
+		box, _ := geom.NewEnvelope([]geom.XY{
+			{X: math.Max(min.X-lonMargin, -180), Y: min.Y - margin},
+			{X: math.Min(max.X+lonMargin, 180), Y: max.Y + margin},
+		})
+
+		p, _ := box.AsGeometry().AsPolygon()
+		for _, hex := range polyfill(p, res) {
+			outline, err := hexOutline(map[h3.H3Index]bool{hex: true})
+			if err == nil && geom.Intersects(outline, g) {
+				covering[hex] = true
+			}
+		}
+	}
+
+	return covering
+}
+
+// polyfill lists the cells at res whose centres are in p
+func polyfill(p geom.Polygon, res int) []h3.H3Index {
+	var gp h3.GeoPolygon
+	for i, ring := range p.Coordinates() {
+		coords := make([]h3.GeoCoord, ring.Length())
+		for j := range coords {
+			xy := ring.GetXY(j)
+			coords[j] = h3.GeoCoord{Latitude: xy.Y, Longitude: xy.X}
+		}
+
+		if i == 0 {
+			gp.Geofence = coords
+		} else {
+			gp.Holes = append(gp.Holes, coords)
+		}
+	}
+
+	return h3.Polyfill(gp, res)
+}
+
+// coveringServer answers surface requests with every hex covering the
+// requested geometry as one feature and the even ones as another. Like
+// the API, it rejects requests over the size limits, and it fails any
+// request covering failAt
+func coveringServer(failAt h3.H3Index, requests, maxInFlight *int) *httptest.Server {
+	var mu sync.Mutex
+	inFlight := 0
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		*requests++
+		inFlight++
+		if inFlight > *maxInFlight {
+			*maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		var req SurfaceReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"message": err.Error()})
+			return
+		}
+
+		all, even := []string{}, []string{}
+		for hex := range coveringHexes(req.Geometry, int(req.Resolution)) {
+			if hex == failAt {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"message":"boom"}`))
+				return
+			}
+
+			all = append(all, h3.ToString(hex))
+			if hex%2 == 0 {
+				even = append(even, h3.ToString(hex))
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"statusCode": 200,
+			"data": []map[string]any{
+				{"hexes": all, "props": map[string]any{"layer": "a"}},
+				{"hexes": even, "props": map[string]any{"layer": "b", "even": true}},
+			},
+		})
+	}))
+}
+
+// tiledTestCases are areas to tile, at resolution 9 and two levels
+// deep unless they say otherwise
+var tiledTestCases = []struct {
+	name        string
+	wkt         string
+	res         uint8
+	opts        *TileOptions
+	minRequests int
+}{
+	{
+		name:        "square",
+		wkt:         "POLYGON((-93.3 44.9,-93.2 44.9,-93.2 45,-93.3 45,-93.3 44.9))",
+		minRequests: 2,
+	},
+	{
+		name:        "sliver and triangle with a hole",
+		wkt:         "MULTIPOLYGON(((-93.3 44.9,-93.1 44.9001,-93.3 44.9002,-93.3 44.9)),((-93 45,-92.9 45,-93 45.1,-93 45),(-92.99 45.01,-92.97 45.01,-92.99 45.03,-92.99 45.01)))",
+		minRequests: 2,
+	},
+	{
+		name:        "either side of the antimeridian",
+		wkt:         "MULTIPOLYGON(((179.95 -0.05,180 -0.05,180 0.05,179.95 0.05,179.95 -0.05)),((-180 -0.05,-179.95 -0.05,-179.95 0.05,-180 0.05,-180 -0.05)))",
+		minRequests: 2,
+	},
+	{
+		name:        "smaller than a hex",
+		wkt:         "POLYGON((-93.2501 44.9501,-93.25 44.9501,-93.25 44.9502,-93.2501 44.9502,-93.2501 44.9501))",
+		minRequests: 1,
+	},
+	{
+		name:        "2° square at resolution 6",
+		wkt:         "POLYGON((-94 44,-92 44,-92 46,-94 46,-94 44))",
+		res:         6,
+		minRequests: 5,
+	},
+	{
+		name:        "2° square at resolution 5",
+		wkt:         "POLYGON((-94 44,-92 44,-92 46,-94 46,-94 44))",
+		res:         5,
+		minRequests: 5,
+	},
+}
+
+func TestSurfaceTiled(mainTest *testing.T) {
+	byLayer := func(f HexFeature) string { return f.Props["layer"].(string) }
+
+	t := assert.New(mainTest)
+	for _, tc := range tiledTestCases {
+		req := exampleSurfaceReq()
+		req.Geometry = mustWKT(tc.wkt)
+
+		opts := &TileOptions{Depth: 2, Workers: 3}
+		if tc.res != 0 {
+			req.Resolution, opts = tc.res, nil
+		}
+
+		// what a single request for the whole area would return
+		expectedAll, expectedEven := map[h3.H3Index]bool{}, map[h3.H3Index]bool{}
+		for hex := range coveringHexes(req.Geometry, int(req.Resolution)) {
+			expectedAll[hex] = true
+			if hex%2 == 0 {
+				expectedEven[hex] = true
+			}
+		}
+
+		requests, maxInFlight := 0, 0
+		srv := coveringServer(0, &requests, &maxInFlight)
+
+		client := &Client{BaseURL: srv.URL}
+		keyed := &TileOptions{FeatureKey: byLayer}
+		if opts != nil {
+			keyed.Depth, keyed.Workers = opts.Depth, opts.Workers
+		}
+
+		merged, err := client.SurfaceTiled(context.Background(), req, keyed)
+		if !t.NoError(err, tc.name) {
+			srv.Close()
+			continue
+		}
+
+		chunks := requests
+		t.GreaterOrEqual(chunks, tc.minRequests, tc.name)
+		t.LessOrEqual(maxInFlight, keyed.workers(), tc.name)
+		t.NotEmpty(expectedAll, tc.name)
+		if t.Len(merged.Data, 2, tc.name) {
+			t.Equal(map[string]any{"layer": "a"}, merged.Data[0].Props, tc.name)
+			t.Equal(expectedAll, merged.Data[0].Hexes, tc.name)
+			t.Equal(map[string]any{"layer": "b", "even": true}, merged.Data[1].Props, tc.name)
+			t.Equal(expectedEven, merged.Data[1].Hexes, tc.name)
+		}
+
+		// without a key, every chunk's features are kept, and
+		// still hold each hex exactly once between them
+		separate, err := client.SurfaceTiled(context.Background(), req, opts)
+		srv.Close()
+
+		if !t.NoError(err, tc.name) {
+			continue
+		}
+
+		t.Len(separate.Data, 2*chunks, tc.name)
+		seen := map[h3.H3Index]int{}
+		for _, f := range separate.Data {
+			if byLayer(f) == "a" {
+				for hex := range f.Hexes {
+					seen[hex]++
+				}
+			}
+		}
+
+		t.Len(seen, len(expectedAll), tc.name)
+		for hex, n := range seen {
+			t.True(expectedAll[hex], tc.name)
+			t.Equal(1, n, tc.name)
+		}
+	}
+}
+
+func TestSurfaceChunks(mainTest *testing.T) {
+	t := assert.New(mainTest)
+	for _, tc := range tiledTestCases {
+		g := mustWKT(tc.wkt)
+		chunks, err := surfaceChunks(g, 3)
+		if !t.NoError(err, tc.name) || !t.NotEmpty(chunks, tc.name) {
+			continue
+		}
+
+		// the pieces fit in a request, stay in their cells and add up
+		// to the whole geometry, so they can't overlap. Areas are planar
+		// here, since sphericalArea changes with collinear vertices
+		var area float64
+		for _, chunk := range chunks {
+			t.Empty(validateSurfaceGeometry(chunk.geometry, true), tc.name)
+			t.GreaterOrEqual(h3.Resolution(chunk.cell), 3, tc.name)
+
+			outline, err := hexOutline(map[h3.H3Index]bool{chunk.cell: true})
+			if t.NoError(err, tc.name) {
+				inside, _ := geom.Intersection(chunk.geometry, outline)
+				t.InEpsilon(chunk.geometry.Area(), inside.Area(), 1e-9, tc.name)
+			}
+
+			mp, _ := asMultiPolygon(chunk.geometry)
+			for i := 0; i < mp.NumPolygons(); i++ {
+				t.Less(mp.PolygonN(i).Envelope().Width(), 180.0, tc.name)
+			}
+
+			area += chunk.geometry.Area()
+		}
+
+		t.InEpsilon(g.Area(), area, 1e-9, tc.name)
+	}
+
+	// pieces too big for a single request are cut along finer cells
+	finer := 0
+	chunks, err := surfaceChunks(mustWKT("POLYGON((-94 44,-92 44,-92 46,-94 46,-94 44))"), 2)
+	if t.NoError(err) {
+		for _, chunk := range chunks {
+			t.Empty(validateSurfaceGeometry(chunk.geometry, true))
+			if h3.Resolution(chunk.cell) > 2 {
+				finer++
+			}
+		}
+	}
+
+	t.Greater(finer, 1)
+}
+
+func TestMergeHexFeatures(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	hexes := h3.KRing(h3.FromGeo(h3.GeoCoord{Latitude: 44.95, Longitude: -93.25}, 9), 1)
+
+	// both chunks send back a hex on their shared edge, and the
+	// features of either chunk have different props
+	pages := [][]HexFeature{
+		{{Hexes: map[h3.H3Index]bool{hexes[0]: true, hexes[1]: true}, Props: map[string]any{"layer": "a", "score": 1.0}}},
+		{{Hexes: map[h3.H3Index]bool{hexes[1]: true, hexes[2]: true, hexes[3]: false}, Props: map[string]any{"layer": "a", "score": 2.0}}},
+	}
+
+	separate := mergeHexFeatures(pages, nil)
+	if t.Len(separate, 2) {
+		t.Equal(map[h3.H3Index]bool{hexes[0]: true, hexes[1]: true}, separate[0].Hexes)
+		t.Equal(map[h3.H3Index]bool{hexes[2]: true}, separate[1].Hexes)
+	}
+
+	merged := mergeHexFeatures(pages, func(f HexFeature) string { return f.Props["layer"].(string) })
+	if t.Len(merged, 1) {
+		t.Equal(map[h3.H3Index]bool{hexes[0]: true, hexes[1]: true, hexes[2]: true}, merged[0].Hexes)
+		t.Equal(1.0, merged[0].Props["score"])
+	}
+}
+
+func TestSurfaceTiledErrors(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	req := exampleSurfaceReq()
+	failAt := h3.FromGeo(h3.GeoCoord{Latitude: 44.95, Longitude: -93.25}, int(req.Resolution))
+
+	requests, maxInFlight := 0, 0
+	srv := coveringServer(failAt, &requests, &maxInFlight)
+	defer srv.Close()
+
+	client := &Client{BaseURL: srv.URL}
+	_, err := client.SurfaceTiled(context.Background(), req, nil)
+	if t.Error(err) {
+		t.Contains(err.Error(), "surface chunk ")
+		t.Contains(err.Error(), "400 boom")
+	}
+
+	// the whole request is checked up front, except for its size
+	req.Layers = nil
+	_, err = client.SurfaceTiled(context.Background(), req, nil)
+	t.EqualError(err, "invalid request: no layers to query")
+}
@@ -0,0 +1,155 @@
+package asl
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Cond is an optional helper for building a layer's where clause out of
+// Eq, In, Gt, Between, And, Or, Not and friends, in prefix notation, e.g.
+//
+//	And(Eq("class", "B"), Not(In("state", "MN", "WI")))
+//
+// becomes
+//
+//	["and", ["=", "class", "B"], ["not", ["in", "state", "MN", "WI"]]]
+//
+// The zero Cond matches everything. Where clauses written by hand don't
+// have to follow this shape; they're sent as they are
+type Cond struct {
+	op    string
+	field string
+	args  []any
+	conds []Cond
+}
+
+// where clause operators
+const (
+	opEq      = "="
+	opNe      = "!="
+	opGt      = ">"
+	opGte     = ">="
+	opLt      = "<"
+	opLte     = "<="
+	opIn      = "in"
+	opBetween = "between"
+	opAnd     = "and"
+	opOr      = "or"
+	opNot     = "not"
+)
+
+// Eq matches features whose field equals v
+func Eq(field string, v any) Cond { return Cond{op: opEq, field: field, args: []any{v}} }
+
+// Ne matches features whose field doesn't equal v
+func Ne(field string, v any) Cond { return Cond{op: opNe, field: field, args: []any{v}} }
+
+// Gt matches features whose field is greater than v
+func Gt(field string, v any) Cond { return Cond{op: opGt, field: field, args: []any{v}} }
+
+// Gte matches features whose field is at least v
+func Gte(field string, v any) Cond { return Cond{op: opGte, field: field, args: []any{v}} }
+
+// Lt matches features whose field is less than v
+func Lt(field string, v any) Cond { return Cond{op: opLt, field: field, args: []any{v}} }
+
+// Lte matches features whose field is at most v
+func Lte(field string, v any) Cond { return Cond{op: opLte, field: field, args: []any{v}} }
+
+// In matches features whose field equals any of vs
+func In(field string, vs ...any) Cond { return Cond{op: opIn, field: field, args: vs} }
+
+// Between matches features whose field is within [lo, hi]
+func Between(field string, lo, hi any) Cond {
+	return Cond{op: opBetween, field: field, args: []any{lo, hi}}
+}
+
+// And matches features matching every one of conds
+func And(conds ...Cond) Cond { return Cond{op: opAnd, conds: conds} }
+
+// Or matches features matching any of conds
+func Or(conds ...Cond) Cond { return Cond{op: opOr, conds: conds} }
+
+// Not matches features that don't match c
+func Not(c Cond) Cond { return Cond{op: opNot, conds: []Cond{c}} }
+
+// IsZero reports whether c is the zero Cond
+func (c Cond) IsZero() bool { return c.op == "" }
+
+// Where returns c in the shape of Layer.Where. The zero Cond is nil
+func (c Cond) Where() []any {
+	if c.IsZero() {
+		return nil
+	}
+
+	if c.field == "" {
+		where := []any{c.op}
+		for _, sub := range c.conds {
+			if !sub.IsZero() {
+				where = append(where, sub.Where())
+			}
+		}
+
+		return where
+	}
+
+	return append([]any{c.op, c.field}, c.args...)
+}
+
+func (c Cond) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Where())
+}
+
+// SetWhere makes c the layer's where clause, after checking
+// that it only refers to the layer's fields
+func (l *Layer) SetWhere(c Cond) error {
+	if err := l.checkCond(c); err != nil {
+		return err
+	}
+
+	l.Where = c.Where()
+	return nil
+}
+
+// checkCond reports the first condition in c that has no field, no
+// values to compare with, or a field that isn't one of the layer's, and
+// the first and, or or not with nothing to combine
+func (l *Layer) checkCond(c Cond) error {
+	switch c.op {
+	case "":
+		return nil
+	case opAnd, opOr, opNot:
+		operands := 0
+		for _, sub := range c.conds {
+			if err := l.checkCond(sub); err != nil {
+				return err
+			}
+
+			if !sub.IsZero() {
+				operands++
+			}
+		}
+
+		if operands == 0 {
+			return fmt.Errorf("layer %q: %q needs at least one condition", l.Code, c.op)
+		}
+
+		return nil
+	}
+
+	if c.field == "" {
+		return fmt.Errorf("layer %q: %q needs a field", l.Code, c.op)
+	}
+
+	if len(c.args) == 0 {
+		return fmt.Errorf("layer %q: %q on %q needs at least one value", l.Code, c.op, c.field)
+	}
+
+	for _, f := range l.Fields {
+		if f == c.field {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("layer %q: where clause refers to %q, which isn't one of its fields %v", l.Code, c.field, l.Fields)
+}
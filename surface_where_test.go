@@ -0,0 +1,140 @@
+package asl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCondWhere(mainTest *testing.T) {
+	testCases := []struct {
+		name     string
+		cond     Cond
+		expected string
+	}{
+		{
+			name:     "zero",
+			expected: `null`,
+		},
+		{
+			name:     "comparisons",
+			cond:     And(Eq("class", "B"), Ne("class", "G"), Gt("floor", 100), Gte("floor", 0), Lt("ceiling", 400.5), Lte("ceiling", 1200)),
+			expected: `["and",["=","class","B"],["!=","class","G"],[">","floor",100],[">=","floor",0],["<","ceiling",400.5],["<=","ceiling",1200]]`,
+		},
+		{
+			name:     "nested",
+			cond:     Or(Between("floor", 0, 400), Not(In("state", "MN", "WI"))),
+			expected: `["or",["between","floor",0,400],["not",["in","state","MN","WI"]]]`,
+		},
+		{
+			name:     "zero conditions are dropped",
+			cond:     And(Cond{}, Eq("class", "B")),
+			expected: `["and",["=","class","B"]]`,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, err := json.Marshal(tc.cond)
+		t.NoError(err, tc.name)
+		t.JSONEq(tc.expected, string(actual), tc.name)
+
+		// the layer serializes the same way
+		buf, err := json.Marshal(Layer{Where: tc.cond.Where()})
+		t.NoError(err, tc.name)
+
+		var layer map[string]json.RawMessage
+		t.NoError(json.Unmarshal(buf, &layer), tc.name)
+		t.JSONEq(tc.expected, string(layer["where"]), tc.name)
+	}
+}
+
+func TestLayerSetWhere(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		cond        Cond
+		expectedErr string
+	}{
+		{
+			name: "known fields",
+			cond: And(Eq("class", "B"), Not(Between("floor", 0, 400))),
+		},
+		{
+			name:        "unknown field",
+			cond:        Or(Eq("class", "B"), Gt("height", 10)),
+			expectedErr: `layer "airspace": where clause refers to "height", which isn't one of its fields [class floor]`,
+		},
+		{
+			name:        "in without values",
+			cond:        In("class"),
+			expectedErr: `layer "airspace": "in" on "class" needs at least one value`,
+		},
+		{
+			name:        "missing field",
+			cond:        Eq("", 1),
+			expectedErr: `layer "airspace": "=" needs a field`,
+		},
+		{
+			name:        "empty and",
+			cond:        And(),
+			expectedErr: `layer "airspace": "and" needs at least one condition`,
+		},
+		{
+			name:        "not of nothing",
+			cond:        Not(Cond{}),
+			expectedErr: `layer "airspace": "not" needs at least one condition`,
+		},
+		{
+			name:        "nested empty or",
+			cond:        And(Eq("class", "B"), Or(Cond{}, Cond{})),
+			expectedErr: `layer "airspace": "or" needs at least one condition`,
+		},
+		{
+			name: "zero operands are dropped",
+			cond: Or(Cond{}, Eq("class", "B")),
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		layer := Layer{Code: "airspace", Fields: []string{"class", "floor"}}
+		err := layer.SetWhere(tc.cond)
+		if tc.expectedErr != "" {
+			t.EqualError(err, tc.expectedErr, tc.name)
+			t.Nil(layer.Where, tc.name)
+			continue
+		}
+
+		t.NoError(err, tc.name)
+		t.Equal(tc.cond.Where(), layer.Where, tc.name)
+	}
+}
+
+func TestSurfaceSendsWhere(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	var actual struct {
+		Layers []struct {
+			Where json.RawMessage `json:"where"`
+		} `json:"layers"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&actual)
+		w.Write([]byte(`{"statusCode":200,"data":[]}`))
+	}))
+	defer srv.Close()
+
+	// hand written clauses aren't held to Cond's shape or the layer's fields
+	req := exampleSurfaceReq()
+	req.Layers[0].Where = []any{"like", "name", "B%"}
+
+	_, err := (&Client{BaseURL: srv.URL}).Surface(context.Background(), req)
+	if t.NoError(err) && t.Len(actual.Layers, 1) {
+		t.JSONEq(`["like","name","B%"]`, string(actual.Layers[0].Where))
+	}
+}
@@ -0,0 +1,158 @@
+package asl
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultExpirySkew is how long before its expiration a token gets
+// refreshed when Client.ExpirySkew isn't set
+const DefaultExpirySkew = time.Minute
+
+type Token struct {
+	AccessToken string    `json:"accessToken"`
+	Expiration  time.Time `json:"expires"`
+	Scopes      string    `json:"scope"`
+}
+
+func (t Token) Expired() bool {
+	return time.Now().After(t.Expiration)
+}
+
+// ExpiresWithin reports whether the token is missing or will expire
+// within d from now. A zero Expiration means the expiry is unknown, as
+// with opaque tokens from a file or the environment, so those are only
+// replaced once the API rejects them
+func (t Token) ExpiresWithin(d time.Duration) bool {
+	if t.AccessToken == "" {
+		return true
+	}
+
+	return !t.Expiration.IsZero() && time.Now().Add(d).After(t.Expiration)
+}
+
+// authState is the bookkeeping behind a client's cached token
+type authState struct {
+	// mu guards the client's Token and everything below
+	mu      sync.Mutex
+	refresh *tokenRefresh
+
+	// scopes are those of the last Authenticate call, when there was one
+	scopes        []string
+	authenticated bool
+}
+
+// authStatesMu serializes creating clients' authState
+var authStatesMu sync.Mutex
+
+func (c *Client) authState() *authState {
+	authStatesMu.Lock()
+	defer authStatesMu.Unlock()
+
+	if c.auth == nil {
+		c.auth = &authState{}
+	}
+
+	return c.auth
+}
+
+// tokenRefresh is a token request in flight. Every goroutine that needs
+// a new token while it's running waits on done instead of starting its own
+type tokenRefresh struct {
+	scopes string
+	done   chan struct{}
+	token  Token
+	err    error
+}
+
+func (c *Client) expirySkew() time.Duration {
+	if c.ExpirySkew == 0 {
+		return DefaultExpirySkew
+	}
+
+	return c.ExpirySkew
+}
+
+// validToken returns an access token to attach to a request, refreshing
+// the cached one first when it's missing, about to expire, or is the
+// token the API just rejected. Clients without credentials get whatever
+// token was set manually
+func (c *Client) validToken(ctx context.Context, rejected string) (string, error) {
+	if !c.canAuthenticate() {
+		return c.CurrentToken().AccessToken, nil
+	}
+
+	a := c.authState()
+	a.mu.Lock()
+	token, scopes := c.Token, c.Scopes
+	if a.authenticated {
+		scopes = a.scopes
+	}
+	a.mu.Unlock()
+
+	if token.AccessToken != rejected && !token.ExpiresWithin(c.expirySkew()) {
+		return token.AccessToken, nil
+	}
+
+	token, err := c.refreshToken(ctx, scopes, rejected)
+	return token.AccessToken, err
+}
+
+// refreshToken fetches a token for scopes and caches it. If a refresh for
+// the same scopes is already running, its result is shared instead,
+// unless it only failed because the context of the caller that started
+// it ended, in which case the next caller in line tries again
+func (c *Client) refreshToken(ctx context.Context, scopes []string, rejected string) (Token, error) {
+	key := strings.Join(scopes, " ")
+	a := c.authState()
+
+	for {
+		a.mu.Lock()
+		r := a.refresh
+		if r == nil {
+			break
+		}
+		a.mu.Unlock()
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		}
+
+		if r.scopes != key {
+			continue
+		}
+
+		if isContextErr(r.err) && ctx.Err() == nil {
+			continue
+		}
+
+		return r.token, r.err
+	}
+
+	r := &tokenRefresh{scopes: key, done: make(chan struct{})}
+	a.refresh = r
+	a.mu.Unlock()
+
+	r.token, r.err = c.fetchToken(ctx, scopes, rejected)
+
+	a.mu.Lock()
+	if r.err == nil {
+		c.Token = r.token
+		a.scopes, a.authenticated = scopes, true
+	}
+	a.refresh = nil
+	a.mu.Unlock()
+
+	close(r.done)
+	return r.token, r.err
+}
+
+// isContextErr reports whether err came from a context ending
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
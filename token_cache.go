@@ -0,0 +1,246 @@
+package asl
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout is how long a FileTokenCache waits on another
+// process' lock when FileTokenCache.LockTimeout isn't set
+const DefaultLockTimeout = 10 * time.Second
+
+// DefaultStaleLockAge is how old a lock must be before it's assumed
+// abandoned when FileTokenCache.StaleLockAge isn't set
+const DefaultStaleLockAge = 2 * time.Minute
+
+// lockPollInterval is how often a locked cache entry is re-checked
+const lockPollInterval = 25 * time.Millisecond
+
+// FileTokenCache persists client credentials tokens to disk so short-lived
+// processes (CLIs, cron jobs) reuse a token until it expires instead of
+// hitting the oauth endpoint on every run. Entries are keyed by base URL,
+// client ID and scopes, written with owner-only permissions, and guarded by
+// a lock file so concurrent processes fetch at most one token between them
+type FileTokenCache struct {
+	// Dir holds the cache entries. Defaults to an "asl" directory
+	// under os.UserCacheDir
+	Dir string
+
+	// LockTimeout bounds how long to wait on another process holding an
+	// entry's lock. Defaults to DefaultLockTimeout
+	LockTimeout time.Duration
+
+	// StaleLockAge is how old a lock must be before it's assumed to be
+	// left over from a crashed process and broken. It should comfortably
+	// exceed the time a token request can take. Defaults to DefaultStaleLockAge
+	StaleLockAge time.Duration
+}
+
+// tokenCacheKey derives a file name from everything that makes a token
+// distinct, without writing the client ID to disk in the clear. Scopes
+// are sorted, since their order doesn't change the token
+func tokenCacheKey(baseURL, clientID string, scopes []string) string {
+	sorted := append([]string(nil), scopes...)
+	sort.Strings(sorted)
+
+	sum := sha256.Sum256([]byte(baseURL + "\x00" + clientID + "\x00" + strings.Join(sorted, " ")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (fc *FileTokenCache) dir() (string, error) {
+	if fc.Dir != "" {
+		return fc.Dir, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "asl"), nil
+}
+
+func (fc *FileTokenCache) lockTimeout() time.Duration {
+	if fc.LockTimeout == 0 {
+		return DefaultLockTimeout
+	}
+
+	return fc.LockTimeout
+}
+
+func (fc *FileTokenCache) staleLockAge() time.Duration {
+	if fc.StaleLockAge == 0 {
+		return DefaultStaleLockAge
+	}
+
+	return fc.StaleLockAge
+}
+
+// get returns the token cached under key, unless it expires within skew or
+// is the token the API just rejected, in which case fetch is called and its
+// result cached. The entry stays locked for the whole lookup
+func (fc *FileTokenCache) get(ctx context.Context, key, rejected string, skew time.Duration, fetch func(context.Context) (Token, error)) (Token, error) {
+	dir, err := fc.dir()
+	if err != nil {
+		return Token{}, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Token{}, err
+	}
+
+	path := filepath.Join(dir, key+".json")
+	unlock, err := fc.lock(ctx, path+".lock")
+	if err != nil {
+		return Token{}, err
+	}
+	defer unlock()
+
+	if t, err := readCachedToken(path); err == nil && t.AccessToken != rejected && !t.ExpiresWithin(skew) {
+		return t, nil
+	}
+
+	t, err := fetch(ctx)
+	if err != nil {
+		return Token{}, err
+	}
+
+	return t, writeCachedToken(path, t)
+}
+
+// lock takes an exclusive lock by creating path with a random owner token
+// in it, waiting for any other holder to remove it. Only the owner's token
+// releases the lock, and locks older than the stale lock age are broken
+func (fc *FileTokenCache) lock(ctx context.Context, path string) (func(), error) {
+	owner, err := lockOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(fc.lockTimeout())
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, err = f.Write(owner)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				removeLock(path, owner)
+				return nil, err
+			}
+
+			return func() { removeLock(path, owner) }, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > fc.staleLockAge() {
+			if held, err := os.ReadFile(path); err == nil {
+				removeLock(path, held)
+				continue
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting on token cache lock " + path)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockOwner returns a token identifying one holder of a lock
+func lockOwner() ([]byte, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	return []byte(hex.EncodeToString(buf)), nil
+}
+
+// removeLock removes the lock at path if it's held by owner. The lock is
+// moved aside before it's checked, so a process that has just taken it over
+// can't lose it in between; if it turns out not to be owner's, it's put back
+func removeLock(path string, owner []byte) {
+	token, err := lockOwner()
+	if err != nil {
+		return
+	}
+
+	aside := path + "." + string(token) + ".stale"
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	defer os.Remove(aside)
+
+	if held, err := os.ReadFile(aside); err == nil && bytes.Equal(held, owner) {
+		return
+	}
+
+	// Link rather than Rename, so a lock taken in the meantime isn't clobbered
+	if err := os.Link(aside, path); err != nil && !errors.Is(err, fs.ErrExist) {
+		os.Rename(aside, path)
+	}
+}
+
+func readCachedToken(path string) (Token, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return Token{}, err
+	}
+
+	var t Token
+	err = json.Unmarshal(buf, &t)
+	return t, err
+}
+
+// writeCachedToken replaces the entry at path atomically, so readers
+// that skip the lock never see a partial write
+func writeCachedToken(path string, t Token) error {
+	buf, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
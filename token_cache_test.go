@@ -0,0 +1,162 @@
+package asl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTokenCache(mainTest *testing.T) {
+	testCases := []struct {
+		name              string
+		expires           time.Time
+		scopes            [][]string
+		staleLock         bool
+		expectedOAuthHits int
+	}{
+		{
+			name:              "second run reuses the cached token",
+			expires:           time.Now().Add(time.Hour),
+			scopes:            [][]string{nil, nil},
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "expiring tokens aren't reused",
+			expires:           time.Now().Add(time.Second),
+			scopes:            [][]string{nil, nil},
+			expectedOAuthHits: 2,
+		},
+		{
+			name:              "scopes are cached separately",
+			expires:           time.Now().Add(time.Hour),
+			scopes:            [][]string{{"a"}, {"b"}, {"a"}},
+			expectedOAuthHits: 2,
+		},
+		{
+			name:              "scope order doesn't matter",
+			expires:           time.Now().Add(time.Hour),
+			scopes:            [][]string{{"a", "b"}, {"b", "a"}},
+			expectedOAuthHits: 1,
+		},
+		{
+			name:              "abandoned locks are broken",
+			expires:           time.Now().Add(time.Hour),
+			scopes:            [][]string{nil},
+			staleLock:         true,
+			expectedOAuthHits: 1,
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		oauthHits := 0
+		srv := authServer(tc.expires, &oauthHits)
+		cache := &FileTokenCache{Dir: mainTest.TempDir(), LockTimeout: 100 * time.Millisecond}
+
+		if tc.staleLock {
+			lock := filepath.Join(cache.Dir, tokenCacheKey(srv.URL, "something", nil)+".json.lock")
+			os.WriteFile(lock, nil, 0o600)
+			os.Chtimes(lock, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+		}
+
+		for _, scopes := range tc.scopes {
+			// a fresh client per run, like separate processes
+			client := &Client{
+				SubscriptionKey: "something",
+				ClientID:        "something",
+				ClientSecret:    "something",
+				BaseURL:         srv.URL,
+				TokenCache:      cache,
+			}
+
+			t.Nil(client.Authenticate(context.Background(), scopes...), tc.name)
+		}
+
+		t.Equal(tc.expectedOAuthHits, oauthHits, tc.name)
+
+		entries, _ := filepath.Glob(filepath.Join(cache.Dir, "*"))
+		for _, entry := range entries {
+			info, err := os.Stat(entry)
+			if t.Nil(err, tc.name) {
+				t.Equal(os.FileMode(0o600), info.Mode().Perm(), tc.name)
+			}
+			t.Equal(".json", filepath.Ext(entry), tc.name+" should leave no lock or temp files behind")
+		}
+
+		srv.Close()
+	}
+}
+
+func TestFileTokenCacheRejectedToken(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	oauthHits := 0
+	srv := authServer(time.Now().Add(time.Hour), &oauthHits)
+	defer srv.Close()
+
+	cache := &FileTokenCache{Dir: mainTest.TempDir()}
+	key := tokenCacheKey(srv.URL, "something", nil)
+	t.Nil(writeCachedToken(filepath.Join(cache.Dir, key+".json"), Token{
+		AccessToken: "revoked",
+		Expiration:  time.Now().Add(time.Hour),
+	}))
+
+	client := &Client{
+		SubscriptionKey: "something",
+		ClientID:        "something",
+		ClientSecret:    "something",
+		BaseURL:         srv.URL,
+		TokenCache:      cache,
+	}
+
+	_, err := client.Surface(context.Background(), exampleSurfaceReq())
+	t.Nil(err)
+	t.Equal(1, oauthHits)
+
+	cached, err := readCachedToken(filepath.Join(cache.Dir, key+".json"))
+	if t.Nil(err) {
+		t.Equal("token-1", cached.AccessToken)
+	}
+}
+
+func TestFileTokenCacheLock(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	cache := &FileTokenCache{Dir: mainTest.TempDir(), LockTimeout: 50 * time.Millisecond}
+	path := filepath.Join(cache.Dir, "entry.json.lock")
+
+	// a slow holder's lock outlives the wait, but isn't stale yet
+	t.Nil(os.WriteFile(path, []byte("other"), 0o600))
+	os.Chtimes(path, time.Now().Add(-time.Second), time.Now().Add(-time.Second))
+
+	_, err := cache.lock(context.Background(), path)
+	if t.Error(err) {
+		t.Contains(err.Error(), "timed out")
+	}
+
+	// and releasing someone else's lock leaves it alone
+	removeLock(path, []byte("mine"))
+
+	held, err := os.ReadFile(path)
+	t.Nil(err)
+	t.Equal("other", string(held))
+
+	// once the holder is gone, the lock is ours until we release it
+	removeLock(path, []byte("other"))
+	unlock, err := cache.lock(context.Background(), path)
+	if t.Nil(err) {
+		_, err = cache.lock(context.Background(), path)
+		t.Error(err)
+
+		unlock()
+		_, err = os.Stat(path)
+		t.True(os.IsNotExist(err))
+	}
+
+	entries, _ := filepath.Glob(filepath.Join(cache.Dir, "*"))
+	t.Empty(entries)
+}
@@ -0,0 +1,148 @@
+package asl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// TokenSource supplies the tokens a Client attaches to its requests.
+// It mirrors golang.org/x/oauth2's TokenSource, so an oauth2 source
+// can be plugged in with a few lines of TokenSourceFunc:
+//
+//	asl.TokenSourceFunc(func(context.Context) (asl.Token, error) {
+//		t, err := src.Token()
+//		if err != nil {
+//			return asl.Token{}, err
+//		}
+//		return asl.Token{AccessToken: t.AccessToken, Expiration: t.Expiry}, nil
+//	})
+type TokenSource interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// TokenSourceFunc adapts a plain function into a TokenSource
+type TokenSourceFunc func(ctx context.Context) (Token, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (Token, error) { return f(ctx) }
+
+// ClientCredentials fetches tokens from the oauth endpoint using the
+// client credentials flow. It's what a Client uses when its TokenSource
+// is unset, but it can also be handed to other clients or wrapped
+type ClientCredentials struct {
+	BaseURL         string
+	SubscriptionKey string
+	ClientID        string
+	ClientSecret    string
+	Scopes          []string
+
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (cc ClientCredentials) Token(ctx context.Context) (Token, error) {
+	c := Client{
+		BaseURL:         cc.BaseURL,
+		SubscriptionKey: cc.SubscriptionKey,
+		ClientID:        cc.ClientID,
+		ClientSecret:    cc.ClientSecret,
+	}
+
+	if cc.HTTPClient != nil {
+		c.HTTPClient = *cc.HTTPClient
+	}
+
+	return c.requestClientCredentials(ctx, cc.Scopes)
+}
+
+// StaticTokenSource always returns t. Once t expires, requests
+// fail until the client is given a new token source
+func StaticTokenSource(t Token) TokenSource {
+	return TokenSourceFunc(func(context.Context) (Token, error) {
+		return t, nil
+	})
+}
+
+// FileTokenSource reads a token from path every time the client needs a
+// new one, so tokens rotated on disk (e.g. by a sidecar) are picked up.
+// The file may hold either a JSON encoded Token or a bare JWT
+func FileTokenSource(path string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (Token, error) {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return Token{}, err
+		}
+
+		return parseToken(buf)
+	})
+}
+
+// EnvTokenSource reads a token from the environment variable name every
+// time the client needs a new one. The variable may hold either a JSON
+// encoded Token or a bare JWT
+func EnvTokenSource(name string) TokenSource {
+	return TokenSourceFunc(func(context.Context) (Token, error) {
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return Token{}, fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return parseToken([]byte(val))
+	})
+}
+
+// parseToken decodes a JSON encoded Token, or failing that treats raw as a
+// JWT and lifts its expiration out of the (unverified) exp claim
+func parseToken(raw []byte) (Token, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return Token{}, fmt.Errorf("empty token")
+	}
+
+	if raw[0] == '{' {
+		var t Token
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return Token{}, err
+		}
+
+		if t.AccessToken == "" {
+			return Token{}, fmt.Errorf("token is missing an access token")
+		}
+
+		return t, nil
+	}
+
+	t := Token{AccessToken: string(raw)}
+
+	parts := strings.Split(t.AccessToken, ".")
+	if len(parts) != 3 {
+		return t, nil
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Token{}, fmt.Errorf("malformed JWT payload: %w", err)
+	}
+
+	var claims struct {
+		Exp   int64  `json:"exp"`
+		Scope string `json:"scope"`
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Token{}, fmt.Errorf("malformed JWT claims: %w", err)
+	}
+
+	if claims.Exp != 0 {
+		t.Expiration = time.Unix(claims.Exp, 0).UTC()
+	}
+
+	t.Scopes = claims.Scope
+	return t, nil
+}
@@ -0,0 +1,177 @@
+package asl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseToken(mainTest *testing.T) {
+	testCases := []struct {
+		name        string
+		arg         []byte
+		expected    Token
+		expectedErr string
+	}{
+		{
+			name:        "empty",
+			arg:         []byte("  \n"),
+			expectedErr: "empty token",
+		},
+		{
+			name: "json token",
+			arg:  []byte(`{"accessToken":"xyz123","expires":"2030-02-03T07:43:22Z","scope":"a b"}`),
+			expected: Token{
+				AccessToken: "xyz123",
+				Expiration:  time.Date(2030, 2, 3, 7, 43, 22, 0, time.UTC),
+				Scopes:      "a b",
+			},
+		},
+		{
+			name:        "json token without an access token",
+			arg:         []byte(`{"expires":"2030-02-03T07:43:22Z"}`),
+			expectedErr: "missing an access token",
+		},
+		{
+			name:     "opaque token",
+			arg:      []byte("opaque-token\n"),
+			expected: Token{AccessToken: "opaque-token"},
+		},
+		{
+			// {"exp":1893456000,"scope":"airhub-api/advisory.read"}
+			name: "jwt",
+			arg:  []byte("eyJhbGciOiJub25lIn0.eyJleHAiOjE4OTM0NTYwMDAsInNjb3BlIjoiYWlyaHViLWFwaS9hZHZpc29yeS5yZWFkIn0.sig"),
+			expected: Token{
+				AccessToken: "eyJhbGciOiJub25lIn0.eyJleHAiOjE4OTM0NTYwMDAsInNjb3BlIjoiYWlyaHViLWFwaS9hZHZpc29yeS5yZWFkIn0.sig",
+				Expiration:  time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+				Scopes:      "airhub-api/advisory.read",
+			},
+		},
+		{
+			name:        "jwt with a garbled payload",
+			arg:         []byte("header.!!!.sig"),
+			expectedErr: "malformed JWT payload",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, actualErr := parseToken(tc.arg)
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		if t.Nil(actualErr, tc.name) {
+			t.Equal(tc.expected, actual, tc.name)
+		}
+	}
+}
+
+func TestTokenSources(mainTest *testing.T) {
+	path := filepath.Join(mainTest.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file"), 0o600); err != nil {
+		mainTest.Fatal(err)
+	}
+
+	mainTest.Setenv("ASL_TEST_TOKEN", `{"accessToken":"from-env","expires":"2100-01-01T00:00:00Z"}`)
+
+	testCases := []struct {
+		name        string
+		source      TokenSource
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "static",
+			source:   StaticTokenSource(Token{AccessToken: "static", Expiration: time.Now().Add(time.Hour)}),
+			expected: "Bearer static",
+		},
+		{
+			name:     "file",
+			source:   FileTokenSource(path),
+			expected: "Bearer from-file",
+		},
+		{
+			name:        "missing file",
+			source:      FileTokenSource(path + ".missing"),
+			expectedErr: "no such file",
+		},
+		{
+			name:     "env",
+			source:   EnvTokenSource("ASL_TEST_TOKEN"),
+			expected: "Bearer from-env",
+		},
+		{
+			name:        "unset env",
+			source:      EnvTokenSource("ASL_TEST_TOKEN_UNSET"),
+			expectedErr: "ASL_TEST_TOKEN_UNSET is not set",
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		var actual string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actual = r.Header.Get("Authorization")
+			w.Write([]byte(`{"statusCode":200,"data":[]}`))
+		}))
+
+		client := &Client{BaseURL: srv.URL, TokenSource: tc.source}
+		_, actualErr := client.Surface(context.Background(), exampleSurfaceReq())
+		srv.Close()
+
+		if tc.expectedErr != "" {
+			if t.Error(actualErr, tc.name) {
+				t.Contains(actualErr.Error(), tc.expectedErr, tc.name)
+			}
+			continue
+		}
+
+		if t.Nil(actualErr, tc.name) {
+			t.Equal(tc.expected, actual, tc.name)
+		}
+	}
+}
+
+func TestTokenWithoutExpiry(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	fetches := 0
+	source := TokenSourceFunc(func(context.Context) (Token, error) {
+		fetches++
+		return Token{AccessToken: fmt.Sprintf("opaque-%d", fetches)}, nil
+	})
+
+	// the first token is revoked after two requests
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 2 && r.Header.Get("Authorization") == "Bearer opaque-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"revoked"}`))
+			return
+		}
+
+		w.Write([]byte(`{"statusCode":200,"data":[]}`))
+	}))
+	defer srv.Close()
+
+	client := &Client{BaseURL: srv.URL, TokenSource: source}
+	for i := 0; i < 4; i++ {
+		_, err := client.Surface(context.Background(), exampleSurfaceReq())
+		t.NoError(err)
+	}
+
+	t.Equal(2, fetches, "a token without an expiry should only be replaced after a 401")
+	t.Equal("opaque-2", client.CurrentToken().AccessToken)
+}
@@ -0,0 +1,158 @@
+package asl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/peterstace/simplefeatures/geom"
+)
+
+// OperationVolume is the 4D space a flight will occupy: an area, an
+// altitude band and a time window. As with advisories, a zero
+// AltitudeUpper means no ceiling and zero times mean unbounded
+type OperationVolume struct {
+	Geometry      geom.Geometry
+	AltitudeLower Altitude
+	AltitudeUpper Altitude
+	StartTime     time.Time
+	EndTime       time.Time
+}
+
+// Conflict is an advisory that intersects an operation volume, along
+// with the part of the volume it overlaps
+type Conflict struct {
+	Advisory Advisory
+
+	// Overlap is the 2D intersection of the advisory and the volume. It
+	// can be a line or point where the two only touch
+	Overlap geom.Geometry
+
+	// AltitudeLower and AltitudeUpper bound the overlapping band, in
+	// feet. A zero AltitudeUpper means neither side has a ceiling
+	AltitudeLower Altitude
+	AltitudeUpper Altitude
+
+	// StartTime and EndTime bound the overlapping window. Zero values
+	// mean neither side bounds it
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// AltitudeReferenceError lists the advisories Conflicts couldn't
+// evaluate because their altitudes are in another reference datum than
+// the volume's. It matches ErrAltitudeReference
+type AltitudeReferenceError struct {
+	AdvisoryIDs []string
+}
+
+func (e *AltitudeReferenceError) Error() string {
+	return fmt.Sprintf("skipped advisories %s: %s", strings.Join(e.AdvisoryIDs, ", "), ErrAltitudeReference)
+}
+
+func (e *AltitudeReferenceError) Unwrap() error { return ErrAltitudeReference }
+
+// Conflicts returns the advisories that intersect vol in all four
+// dimensions. Altitude bands that only touch count as overlapping, to
+// err on the side of caution, while time windows that only touch don't.
+// Advisories whose altitudes are in another reference datum than vol's
+// can't be compared; they're skipped and listed in an
+// *AltitudeReferenceError, returned along with the other conflicts
+func Conflicts(vol OperationVolume, advisories []Advisory) ([]Conflict, error) {
+	volEnv := vol.Geometry.Envelope()
+
+	var conflicts []Conflict
+	var skipped []string
+	for _, a := range advisories {
+		lower, upper, ok, err := overlapAltitude(vol.AltitudeLower, vol.AltitudeUpper, a.AltitudeLower, a.AltitudeUpper)
+		if err != nil {
+			skipped = append(skipped, a.ID)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		start, end, ok := overlapTime(vol.StartTime, vol.EndTime, a.StartTime, a.EndTime)
+		if !ok {
+			continue
+		}
+
+		// bounding boxes first, they're far cheaper than the real thing
+		if !volEnv.Intersects(a.Geometry.Envelope()) || !geom.Intersects(vol.Geometry, a.Geometry) {
+			continue
+		}
+
+		overlap, err := geom.Intersection(vol.Geometry, a.Geometry)
+		if err != nil {
+			return nil, fmt.Errorf("intersecting advisory %s: %w", a.ID, err)
+		}
+
+		conflicts = append(conflicts, Conflict{
+			Advisory:      a,
+			Overlap:       overlap,
+			AltitudeLower: lower,
+			AltitudeUpper: upper,
+			StartTime:     start,
+			EndTime:       end,
+		})
+	}
+
+	if len(skipped) > 0 {
+		return conflicts, &AltitudeReferenceError{AdvisoryIDs: skipped}
+	}
+
+	return conflicts, nil
+}
+
+// overlapAltitude intersects two closed altitude bands, where a zero
+// upper bound means no ceiling. Unset bounds take no part in the datum
+// check, but every bound that's set, 0 included, must share one. The
+// overlap is returned in feet
+func overlapAltitude(lower1, upper1, lower2, upper2 Altitude) (Altitude, Altitude, bool, error) {
+	var ref AltitudeReference
+	var refAlt Altitude
+	for _, alt := range []Altitude{lower1, upper1, lower2, upper2} {
+		switch {
+		case alt.IsZero():
+		case ref == "":
+			ref, refAlt = alt.reference(), alt
+		case alt.reference() != ref:
+			return Altitude{}, Altitude{}, false, fmt.Errorf("%s and %s: %w", refAlt, alt, ErrAltitudeReference)
+		}
+	}
+
+	if ref == "" {
+		ref = APIAltitudeReference
+	}
+
+	lower := lower1.Feet()
+	if l := lower2.Feet(); l > lower {
+		lower = l
+	}
+
+	upper := upper1.Feet()
+	if u := upper2.Feet(); upper == 0 || (u != 0 && u < upper) {
+		upper = u
+	}
+
+	band := func(v float64) Altitude { return Altitude{Value: v, Unit: Feet, Reference: ref} }
+	return band(lower), band(upper), upper == 0 || lower <= upper, nil
+}
+
+// overlapTime intersects two half-open time windows, where zero
+// times leave that end unbounded
+func overlapTime(start1, end1, start2, end2 time.Time) (time.Time, time.Time, bool) {
+	start := start1
+	if start2.After(start) {
+		start = start2
+	}
+
+	end := end1
+	if end.IsZero() || (!end2.IsZero() && end2.Before(end)) {
+		end = end2
+	}
+
+	return start, end, end.IsZero() || start.Before(end)
+}
@@ -0,0 +1,174 @@
+package asl
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/peterstace/simplefeatures/geom"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustWKT(wkt string) geom.Geometry {
+	g, err := geom.UnmarshalWKT(wkt)
+	if err != nil {
+		panic(err)
+	}
+
+	return g
+}
+
+func TestConflicts(mainTest *testing.T) {
+	noon := time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC)
+	vol := OperationVolume{
+		Geometry:      mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+		AltitudeLower: FeetAGL(0),
+		AltitudeUpper: FeetAGL(400),
+		StartTime:     noon,
+		EndTime:       noon.Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name            string
+		advisory        Advisory
+		expectedOverlap string
+		expectedLower   Altitude
+		expectedUpper   Altitude
+		expectedStart   time.Time
+		expectedEnd     time.Time
+	}{
+		{
+			name: "overlapping in every dimension",
+			advisory: Advisory{
+				Geometry:      mustWKT("POLYGON((1 1,3 1,3 3,1 3,1 1))"),
+				AltitudeLower: FeetAGL(200),
+				AltitudeUpper: FeetAGL(1000),
+				StartTime:     noon.Add(30 * time.Minute),
+				EndTime:       noon.Add(2 * time.Hour),
+			},
+			expectedOverlap: "POLYGON((1 1,2 1,2 2,1 2,1 1))",
+			expectedLower:   FeetAGL(200),
+			expectedUpper:   FeetAGL(400),
+			expectedStart:   noon.Add(30 * time.Minute),
+			expectedEnd:     noon.Add(time.Hour),
+		},
+		{
+			name: "permanent advisory without a ceiling",
+			advisory: Advisory{
+				Geometry: mustWKT("POLYGON((-1 -1,5 -1,5 5,-1 5,-1 -1))"),
+			},
+			expectedOverlap: "POLYGON((0 0,2 0,2 2,0 2,0 0))",
+			expectedLower:   FeetAGL(0),
+			expectedUpper:   FeetAGL(400),
+			expectedStart:   noon,
+			expectedEnd:     noon.Add(time.Hour),
+		},
+		{
+			name: "touching altitude bands conflict",
+			advisory: Advisory{
+				Geometry:      mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+				AltitudeLower: FeetAGL(400),
+				AltitudeUpper: FeetAGL(500),
+			},
+			expectedOverlap: "POLYGON((0 0,2 0,2 2,0 2,0 0))",
+			expectedLower:   FeetAGL(400),
+			expectedUpper:   FeetAGL(400),
+			expectedStart:   noon,
+			expectedEnd:     noon.Add(time.Hour),
+		},
+		{
+			name: "above the volume",
+			advisory: Advisory{
+				Geometry:      mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+				AltitudeLower: FeetAGL(401),
+			},
+		},
+		{
+			name: "ends before the volume starts",
+			advisory: Advisory{
+				Geometry: mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+				EndTime:  noon,
+			},
+		},
+		{
+			name: "starts after the volume ends",
+			advisory: Advisory{
+				Geometry:  mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+				StartTime: noon.Add(time.Hour),
+			},
+		},
+		{
+			name: "disjoint area",
+			advisory: Advisory{
+				Geometry: mustWKT("POLYGON((3 3,4 3,4 4,3 4,3 3))"),
+			},
+		},
+		{
+			name: "disjoint area with overlapping bounding boxes",
+			advisory: Advisory{
+				Geometry: mustWKT("LINESTRING(1.8 2.5,2.5 1.8)"),
+			},
+		},
+	}
+
+	t := assert.New(mainTest)
+	for _, tc := range testCases {
+		actual, actualErr := Conflicts(vol, []Advisory{tc.advisory})
+		if !t.Nil(actualErr, tc.name) {
+			continue
+		}
+
+		if tc.expectedOverlap == "" {
+			t.Empty(actual, tc.name)
+			continue
+		}
+
+		if !t.Len(actual, 1, tc.name) {
+			continue
+		}
+
+		t.True(geom.ExactEquals(mustWKT(tc.expectedOverlap), actual[0].Overlap, geom.IgnoreOrder), tc.name+": "+actual[0].Overlap.AsText())
+		t.Equal(tc.expectedLower, actual[0].AltitudeLower, tc.name)
+		t.Equal(tc.expectedUpper, actual[0].AltitudeUpper, tc.name)
+		t.Equal(tc.expectedStart, actual[0].StartTime, tc.name)
+		t.Equal(tc.expectedEnd, actual[0].EndTime, tc.name)
+	}
+}
+
+func TestConflictsAcrossDatums(mainTest *testing.T) {
+	t := assert.New(mainTest)
+
+	vol := OperationVolume{
+		Geometry:      mustWKT("POLYGON((0 0,2 0,2 2,0 2,0 0))"),
+		AltitudeUpper: MetersAGL(120),
+	}
+
+	// meters and feet mix fine: 120m is just under 394ft
+	actual, err := Conflicts(vol, []Advisory{{Geometry: vol.Geometry, AltitudeLower: FeetAGL(400)}})
+	if t.Nil(err) {
+		t.Len(actual, 0)
+	}
+
+	actual, err = Conflicts(vol, []Advisory{{Geometry: vol.Geometry, AltitudeLower: FeetAGL(390)}})
+	if t.Nil(err) && t.Len(actual, 1) {
+		t.InDelta(393.7, actual[0].AltitudeUpper.Feet(), 0.01)
+	}
+
+	// datums don't, even at zero, but one advisory in another
+	// datum doesn't stop the rest being evaluated
+	actual, err = Conflicts(vol, []Advisory{
+		{ID: "msl", Geometry: vol.Geometry, AltitudeUpper: Altitude{Value: 1200, Unit: Feet, Reference: MSL}},
+		{ID: "agl", Geometry: vol.Geometry, AltitudeUpper: FeetAGL(1200)},
+		{ID: "msl floor", Geometry: vol.Geometry, AltitudeLower: Altitude{Reference: MSL}},
+	})
+	t.True(errors.Is(err, ErrAltitudeReference))
+
+	var refErr *AltitudeReferenceError
+	if t.True(errors.As(err, &refErr)) {
+		t.Equal([]string{"msl", "msl floor"}, refErr.AdvisoryIDs)
+	}
+
+	if t.Len(actual, 1) {
+		t.Equal("agl", actual[0].Advisory.ID)
+	}
+}